@@ -1,9 +1,7 @@
 package main
 
 import (
-	"bytes"
 	"encoding/csv"
-	"encoding/gob"
 	"errors"
 	"fmt"
 	"os"
@@ -11,6 +9,7 @@ import (
 	"time"
 
 	"github.com/taurusgroup/multi-party-sig/internal/test"
+	"github.com/taurusgroup/multi-party-sig/pkg/address"
 	"github.com/taurusgroup/multi-party-sig/pkg/ecdsa"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
 	"github.com/taurusgroup/multi-party-sig/pkg/party"
@@ -23,8 +22,7 @@ import (
 
 	// cuckoo "mpc-wallet-multiple-filters/filters/cuckoo"
 	"github.com/bits-and-blooms/bloom/v3"
-
-	"golang.org/x/crypto/sha3"
+	"github.com/taurusgroup/multi-party-sig/filters/envelope"
 )
 
 var lookupAddress string
@@ -45,38 +43,30 @@ func addCryptoAddressToFilter(cryptoAddress string) {
 	filter.Add([]byte(cryptoAddress))
 }
 
-// AppendFilterToMessage appends the bloom filter to the given message.
+// AppendFilterToMessage frames the given message and the bloom filter in an envelope.
+// The filter is encoded by bits-and-blooms, so it must be decoded with the same library.
 func AppendFilterToMessage(m []byte, filter *bloom.BloomFilter) ([]byte, error) {
-	var buffer bytes.Buffer
-	encoder := gob.NewEncoder(&buffer)
-
-	// Serialize the filter.
-	err := encoder.Encode(filter)
+	data, err := filter.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
-
-	// Append the serialized filter to the message.
-	return append(m, buffer.Bytes()...), nil
+	return envelope.Encode(envelope.Envelope{Type: envelope.Bloom, Message: m, Filter: data})
 }
 
-// ExtractFilterFromMessage extracts the bloom filter from the appended message.
+// ExtractFilterFromMessage extracts the message and the bloom filter from an envelope.
 func ExtractFilterFromMessage(appendedMessage []byte) ([]byte, *bloom.BloomFilter, error) {
-	buffer := bytes.NewBuffer(appendedMessage)
-	decoder := gob.NewDecoder(buffer)
+	e, err := envelope.Decode(appendedMessage)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e.Type != envelope.Bloom {
+		return nil, nil, fmt.Errorf("expected a bloom filter, found %s", e.Type)
+	}
 	var filter bloom.BloomFilter
-
-	// Try decoding from the end until it succeeds.
-	for i := len(appendedMessage); i >= 0; i-- {
-		buffer = bytes.NewBuffer(appendedMessage[i:])
-		decoder = gob.NewDecoder(buffer)
-		err := decoder.Decode(&filter)
-		if err == nil {
-			// Decoding succeeded, return the original message and the decoded filter.
-			return appendedMessage[:i], &filter, nil
-		}
+	if err := filter.UnmarshalBinary(e.Filter); err != nil {
+		return nil, nil, err
 	}
-	return nil, nil, errors.New("could not decode the bloom filter from the message")
+	return e.Message, &filter, nil
 }
 
 func checkCryptoAddressInFilter(address string) bool {
@@ -85,24 +75,16 @@ func checkCryptoAddressInFilter(address string) bool {
 	return filter.Test([]byte(address))
 }
 
-// Given a public key, this function will compute the Ethereum address
-func PublicKeyToCryptoAddress(pub []byte) string {
-	hash := sha3.NewLegacyKeccak256()
-	hash.Write(pub[1:]) // remove EC prefix 04
-	return fmt.Sprintf("0x%x", hash.Sum(nil)[12:])
-}
-
-func ConvertToCryptoAddress(s curve.Scalar) (string, error) {
-	// Convert the scalar to bytes
-	data, err := s.MarshalBinary()
+// ConvertToCryptoAddress returns the Ethereum address of a public key,
+// such as the shared public key c.PublicPoint() of a cmp.Config.
+func ConvertToCryptoAddress(public curve.Point) (string, error) {
+	addr, err := address.Ethereum(public)
 	if err != nil {
 		return "", err
 	}
-
-	// For crypto address generation, we treat the scalar bytes as a public key.
-	// This might not be accurate in a real-world scenario. But just to demonstrate:
-	return PublicKeyToCryptoAddress(data), nil
+	return addr.Hex(), nil
 }
+
 func XOR(id party.ID, ids party.IDSlice, n *test.Network) error {
 	h, err := protocol.NewMultiHandler(example.StartXOR(id, ids), nil)
 	if err != nil {
@@ -152,7 +134,7 @@ func CMPSign(c *cmp.Config, m []byte, signers party.IDSlice, n *test.Network, pl
 	}
 	test.HandlerLoop(c.ID, h, n)
 
-	cryptoAddress, err := ConvertToCryptoAddress(c.ECDSA)
+	cryptoAddress, err := ConvertToCryptoAddress(c.PublicPoint())
 	lookupAddress = cryptoAddress
 	if err != nil {
 		fmt.Println("Error:", err)
@@ -330,7 +312,7 @@ func All(id party.ID, ids party.IDSlice, threshold int, message []byte, n *test.
 	// print ids
 	// fmt.Println(refreshConfig.ID, refreshConfig.ECDSA)
 	// // Usage
-	// address, err := ConvertToCryptoAddress(refreshConfig.ECDSA)
+	// address, err := ConvertToCryptoAddress(refreshConfig.PublicPoint())
 	// if err != nil {
 	// 	fmt.Println("Error:", err)
 	// 	return err
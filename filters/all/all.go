@@ -0,0 +1,15 @@
+// Package all registers every filter implemented in this module.
+//
+// The registry of package filters only knows the filters whose package was imported,
+// so New, Plans and PlanBudget depend on the imports of the program. Importing this
+// package makes them consider all the built-in filters:
+//
+//	import _ "github.com/taurusgroup/multi-party-sig/filters/all"
+package all
+
+import (
+	_ "github.com/taurusgroup/multi-party-sig/filters/bloom"
+	_ "github.com/taurusgroup/multi-party-sig/filters/cuckoo"
+	_ "github.com/taurusgroup/multi-party-sig/filters/gcs"
+	_ "github.com/taurusgroup/multi-party-sig/filters/xor"
+)
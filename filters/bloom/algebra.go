@@ -0,0 +1,98 @@
+package bloom
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+// ErrIncompatible is returned when combining filters with different parameters or hashers.
+var ErrIncompatible = errors.New("bloom: filters are not compatible")
+
+// compatible returns an error wrapping ErrIncompatible if filters with these parameters can't be combined.
+func compatible(m1, k1 uint, doubleHashing1 bool, h1 *hasher.Hasher, m2, k2 uint, doubleHashing2 bool, h2 *hasher.Hasher) error {
+	switch {
+	case m1 != m2 || k1 != k2:
+		return fmt.Errorf("%w: %d bits and %d hashes, and %d bits and %d hashes", ErrIncompatible, m1, k1, m2, k2)
+	case doubleHashing1 != doubleHashing2:
+		return fmt.Errorf("%w: different hashing schemes", ErrIncompatible)
+	case !h1.Equal(h2):
+		return fmt.Errorf("%w: different hash functions or keys", ErrIncompatible)
+	}
+	return nil
+}
+
+// Compatible returns an error wrapping ErrIncompatible if other can't be combined with bf.
+//
+// Both filters must have the same number of bits and hash functions, and derive
+// positions the same way, with the same hash function and key.
+func (bf *Bloom) Compatible(other *Bloom) error {
+	return compatible(bf.M, bf.K, bf.DoubleHashing, bf.hasher, other.M, other.K, other.DoubleHashing, other.hasher)
+}
+
+// estimateCount estimates the number of items in the filter from the number of bits set,
+// using -M/K ln(1 - X/M), see https://doi.org/10.1080/15427951.2004.10129096.
+func (bf *Bloom) estimateCount() uint {
+	set := 0
+	for _, b := range bf.bits {
+		set += bits.OnesCount8(b)
+	}
+	if uint(set) == bf.M {
+		// the estimate is infinite, the filter matches everything anyway
+		return bf.M
+	}
+	m, k := float64(bf.M), float64(bf.K)
+	return uint(math.Round(-m / k * math.Log(1-float64(set)/m)))
+}
+
+// Union adds the items of other to bf, which is the bitwise OR of both filters.
+//
+// Since items present in both filters can't be told apart, the number of items
+// is estimated from the number of bits set afterwards.
+func (bf *Bloom) Union(other *Bloom) error {
+	if err := bf.Compatible(other); err != nil {
+		return err
+	}
+	for i := range bf.bits {
+		bf.bits[i] |= other.bits[i]
+	}
+	bf.count = bf.estimateCount()
+	return nil
+}
+
+// Intersect removes from bf the bits which are not set in other, which is the bitwise AND of both filters.
+//
+// The result contains every item present in both filters, but it may match items present
+// in only one of them with a higher probability than the false positive rate of either filter.
+// The number of items is estimated from the number of bits set afterwards.
+func (bf *Bloom) Intersect(other *Bloom) error {
+	if err := bf.Compatible(other); err != nil {
+		return err
+	}
+	for i := range bf.bits {
+		bf.bits[i] &= other.bits[i]
+	}
+	bf.count = bf.estimateCount()
+	return nil
+}
+
+// Union returns a new filter holding the union of filters, which must be compatible.
+//
+// This is how a coordinator combines the filters built by every party of a session
+// into a single group filter. None of the filters are modified.
+func Union(filters ...*Bloom) (*Bloom, error) {
+	if len(filters) == 0 {
+		return nil, errors.New("bloom: no filters to combine")
+	}
+	out := *filters[0]
+	out.bits = append([]byte{}, filters[0].bits...)
+	for _, f := range filters[1:] {
+		if err := out.Union(f); err != nil {
+			return nil, err
+		}
+	}
+	return &out, nil
+}
@@ -0,0 +1,91 @@
+package bloom
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+func TestUnion(t *testing.T) {
+	parties := make([]*Bloom, 3)
+	all := NewBloomFilter(300, 0.01)
+	for p := range parties {
+		parties[p] = NewBloomFilter(300, 0.01)
+		for i := 0; i < 100; i++ {
+			item := fmt.Sprint(p*100 + i)
+			_ = parties[p].Add(item)
+			_ = all.Add(item)
+		}
+	}
+
+	group, err := Union(parties...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the union is the filter we'd get by adding every item to a single filter
+	if !bytes.Equal(group.bits, all.bits) {
+		t.Error("union differs from a filter holding every item")
+	}
+	if group.Len() < 280 || group.Len() > 320 {
+		t.Errorf("estimated length is %d, expected about 300", group.Len())
+	}
+	// the inputs are left untouched
+	if parties[0].Test("150") && parties[0].Test("250") {
+		t.Error("union modified its inputs")
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	a := NewBloomFilter(100, 0.001)
+	b := NewBloomFilter(100, 0.001)
+	for i := 0; i < 100; i++ {
+		_ = a.Add(fmt.Sprint(i))
+		_ = b.Add(fmt.Sprint(i + 50))
+	}
+	if err := a.Intersect(b); err != nil {
+		t.Fatal(err)
+	}
+	for i := 50; i < 100; i++ {
+		if !a.Test(fmt.Sprint(i)) {
+			t.Errorf("%d missing from the intersection", i)
+		}
+	}
+	falsePositives := 0
+	for i := 0; i < 50; i++ {
+		if a.Test(fmt.Sprint(i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 5 {
+		t.Errorf("%d items of a only are in the intersection", falsePositives)
+	}
+}
+
+func TestIncompatible(t *testing.T) {
+	key := hasher.DeriveKey("algebra test", []byte("wallet secret"))
+	keyed, _ := hasher.New(hasher.SHA1, key)
+	otherKey, _ := hasher.New(hasher.SHA1, hasher.DeriveKey("algebra test", []byte("other secret")))
+
+	bf := NewBloomFilter(100, 0.01, WithHasher(keyed))
+	incompatible := []*Bloom{
+		NewBloomFilter(1000, 0.01, WithHasher(keyed)),
+		NewBloomFilter(100, 0.001, WithHasher(keyed)),
+		NewBloomFilter(100, 0.01, WithHasher(keyed), WithDoubleHashing()),
+		NewBloomFilter(100, 0.01),
+		NewBloomFilter(100, 0.01, WithHasher(otherKey)),
+	}
+	for _, other := range incompatible {
+		if err := bf.Union(other); !errors.Is(err, ErrIncompatible) {
+			t.Errorf("union with %+v: expected ErrIncompatible, got %v", other, err)
+		}
+		if err := bf.Intersect(other); !errors.Is(err, ErrIncompatible) {
+			t.Errorf("intersection with %+v: expected ErrIncompatible, got %v", other, err)
+		}
+	}
+	if _, err := Union(); err == nil {
+		t.Error("union of no filters should fail")
+	}
+}
@@ -1,25 +1,198 @@
-// Documentation:
+// Based on:
 // https://pkg.go.dev/github.com/bits-and-blooms/bloom/v3
+// https://www.eecs.harvard.edu/~michaelm/postscripts/rsa2008.pdf (double hashing)
 
-package main
+package bloom
 
 import (
-	"fmt"
+	"encoding/binary"
+	"math"
 
-	"github.com/bits-and-blooms/bloom/v3"
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
 )
 
-func main() {
+// maxHashes bounds the number of hash functions we accept when decoding a filter.
+// An (n, e) construction only reaches it for e < 2^-32.
+const maxHashes = 32
 
-	// Create a new Bloom Filter with 1,000,000 items and 0.01% false positive rate
-	bf := bloom.NewWithEstimates(3, 0.01)
-	bf.Add([]byte("Hello, world!"))
+// Bloom is a Bloom filter of M bits, where each item sets K of them.
+type Bloom struct {
+	M uint // number of bits
+	K uint // number of hash functions
+	N uint // number of items - filter capacity
+	// DoubleHashing is true if the K positions are derived from a single digest, see positions.
+	DoubleHashing bool
 
-	bf.K()
-	// print the value fo k
-	fmt.Println(bloom.EstimateParameters(3, 0.01))
-	// Test for existence (false positive)
-	if bf.Test([]byte("Hello, orld!")) {
-		fmt.Println("Exists!")
+	// hasher derives bit positions from items
+	hasher *hasher.Hasher
+	// bits holds the M bits of the filter, least significant bit first
+	bits []byte
+	// count is the number of items added so far
+	count uint
+}
+
+// Option enables an optional feature when creating a filter.
+type Option func(*options)
+
+type options struct {
+	doubleHashing bool
+	hasher        *hasher.Hasher
+}
+
+func newOptions(opts []Option) options {
+	o := options{hasher: hasher.Default}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithDoubleHashing derives all K bit positions from a single digest,
+// instead of computing a digest for every group of positions.
+// Adding and testing items is faster, for the same false positive rate asymptotically.
+func WithDoubleHashing() Option {
+	return func(o *options) {
+		o.doubleHashing = true
+	}
+}
+
+// WithHasher replaces the default SHA-1 hash with h.
+//
+// With a keyed hasher, the filter can only be queried by someone who knows the key,
+// which must be passed again to EmptyBloomFilter when decoding it.
+func WithHasher(h *hasher.Hasher) Option {
+	return func(o *options) {
+		o.hasher = h
+	}
+}
+
+// EstimateParameters returns the number of bits m and hash functions k
+// of a filter holding n items with false positive rate e:
+//
+//	m = ceil(-n ln(e) / ln(2)²)
+//	k = ceil(ln(2) m / n)
+//
+// These are the same parameters as github.com/bits-and-blooms/bloom, except that
+// k is at most 32, and that a rate e >= 1, which any filter satisfies, gives the minimal
+// filter m = k = 1.
+func EstimateParameters(n uint, e float64) (m, k uint) {
+	if n == 0 {
+		n = 1
+	}
+	if e >= 1 {
+		return 1, 1
+	}
+	if e <= 0 {
+		e = math.SmallestNonzeroFloat64
+	}
+	m = uint(math.Ceil(-float64(n) * math.Log(e) / (math.Ln2 * math.Ln2)))
+	k = uint(math.Ceil(math.Ln2 * float64(m) / float64(n)))
+	if m == 0 {
+		m = 1
+	}
+	if k == 0 {
+		k = 1
+	}
+	if k > maxHashes {
+		k = maxHashes
+	}
+	return m, k
+}
+
+// NewBloomFilter creates a Bloom filter with capacity n and false positive rate e,
+// see EstimateParameters.
+func NewBloomFilter(n uint, e float64, opts ...Option) *Bloom {
+	o := newOptions(opts)
+	m, k := EstimateParameters(n, e)
+	return &Bloom{
+		M:             m,
+		K:             k,
+		N:             n,
+		DoubleHashing: o.doubleHashing,
+		hasher:        o.hasher,
+		bits:          make([]byte, (m+7)/8),
+	}
+}
+
+// EmptyBloomFilter returns a filter with no parameters, to be filled by UnmarshalBinary.
+//
+// The options are only needed to decode a filter built with a keyed hasher.
+func EmptyBloomFilter(opts ...Option) *Bloom {
+	o := newOptions(opts)
+	return &Bloom{hasher: o.hasher}
+}
+
+// positions returns the K bit positions of input.
+func (bf *Bloom) positions(input string) []uint64 {
+	return positions(bf.hasher, bf.M, bf.K, bf.DoubleHashing, input)
+}
+
+// positions returns k positions in [0, m) for input.
+//
+// By default, the digests of 0 || input, 1 || input, ... are concatenated,
+// and each position is read from 8 bytes of the result.
+//
+// With double hashing, a single digest of input is computed, and its first 8 bytes
+// are split into two 32-bit values h1 and h2, giving positions h1 + i*h2 mod m.
+// h2 is forced to be odd, otherwise h2 = 0 would give the same position k times.
+func positions(h *hasher.Hasher, m, k uint, doubleHashing bool, input string) []uint64 {
+	out := make([]uint64, k)
+	mod := uint64(m)
+
+	if doubleHashing {
+		digest := h.Sum([]byte(input))
+		h1 := uint64(binary.BigEndian.Uint32(digest[0:4]))
+		h2 := uint64(binary.BigEndian.Uint32(digest[4:8])) | 1
+		for i := range out {
+			out[i] = (h1 + uint64(i)*h2) % mod
+		}
+		return out
+	}
+
+	buf := make([]byte, 1+len(input))
+	copy(buf[1:], input)
+	var digest []byte
+	for i := range out {
+		if len(digest) < 8 {
+			digest = h.Sum(buf)
+			buf[0]++
+		}
+		out[i] = binary.BigEndian.Uint64(digest) % mod
+		digest = digest[8:]
 	}
+	return out
+}
+
+// Add inserts an item into the filter.
+//
+// A Bloom filter never refuses an item, its false positive rate just degrades past
+// its capacity, so the error is always nil.
+func (bf *Bloom) Add(input string) error {
+	for _, p := range bf.positions(input) {
+		bf.bits[p/8] |= 1 << (p % 8)
+	}
+	bf.count++
+	return nil
+}
+
+// Test returns true if needle may be in the filter,
+// and false if it definitely isn't.
+func (bf *Bloom) Test(needle string) bool {
+	for _, p := range bf.positions(needle) {
+		if bf.bits[p/8]&(1<<(p%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns the number of items added to the filter.
+func (bf *Bloom) Count() uint {
+	return bf.count
+}
+
+// FalsePositiveRate returns the expected false positive rate (1 - e^(-K count / M))^K
+// given the number of items added so far.
+func (bf *Bloom) FalsePositiveRate() float64 {
+	return expectedRate(bf.M, bf.K, bf.count)
 }
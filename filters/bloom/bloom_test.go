@@ -0,0 +1,85 @@
+package bloom
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+func TestEstimateParameters(t *testing.T) {
+	tests := []struct {
+		n    uint
+		e    float64
+		m, k uint
+	}{
+		// same values as bits-and-blooms/bloom
+		{3, 0.01, 29, 7},
+		{1000, 0.01, 9586, 7},
+		{1000, 0.001, 14378, 10},
+		{1, 0.5, 2, 2},
+		{1, 1e-12, 58, maxHashes},
+		{1000, 1, 1, 1},
+		{1000, 2, 1, 1},
+	}
+	for _, tt := range tests {
+		if m, k := EstimateParameters(tt.n, tt.e); m != tt.m || k != tt.k {
+			t.Errorf("EstimateParameters(%d, %v) = (%d, %d), expected (%d, %d)", tt.n, tt.e, m, k, tt.m, tt.k)
+		}
+	}
+}
+
+func TestAddTest(t *testing.T) {
+	for _, doubleHashing := range []bool{false, true} {
+		var opts []Option
+		if doubleHashing {
+			opts = append(opts, WithDoubleHashing())
+		}
+		bf := NewBloomFilter(1000, 0.01, opts...)
+		for i := 0; i < 1000; i++ {
+			_ = bf.Add(fmt.Sprintf("0x%040d", i))
+		}
+		if bf.Count() != 1000 {
+			t.Errorf("count is %d, expected 1000", bf.Count())
+		}
+		for i := 0; i < 1000; i++ {
+			if !bf.Test(fmt.Sprintf("0x%040d", i)) {
+				t.Fatalf("double hashing %v: item %d missing", doubleHashing, i)
+			}
+		}
+
+		falsePositives := 0
+		for i := 1000; i < 11000; i++ {
+			if bf.Test(fmt.Sprintf("0x%040d", i)) {
+				falsePositives++
+			}
+		}
+		// expect ~100, leave plenty of room
+		if falsePositives > 200 {
+			t.Errorf("double hashing %v: %d false positives out of 10000", doubleHashing, falsePositives)
+		}
+	}
+}
+
+func TestHashers(t *testing.T) {
+	secret := hasher.DeriveKey("bloom test", []byte("wallet secret"))
+	for _, id := range []hasher.ID{hasher.SHA1, hasher.SHA256, hasher.BLAKE3, hasher.FNV64, hasher.SipHash} {
+		for _, key := range [][]byte{nil, secret} {
+			h, err := hasher.New(id, key)
+			if err != nil {
+				continue
+			}
+			for _, opts := range [][]Option{{WithHasher(h)}, {WithHasher(h), WithDoubleHashing()}} {
+				bf := NewBloomFilter(50, 0.001, opts...)
+				for i := 0; i < 50; i++ {
+					_ = bf.Add(fmt.Sprint(i))
+				}
+				for i := 0; i < 50; i++ {
+					if !bf.Test(fmt.Sprint(i)) {
+						t.Errorf("%s (keyed: %v): %d missing", id, h.Keyed(), i)
+					}
+				}
+			}
+		}
+	}
+}
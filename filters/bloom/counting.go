@@ -0,0 +1,244 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+// maxCounter is the largest value of a 4-bit counter.
+const maxCounter = 15
+
+// Counting is a counting Bloom filter, which supports deleting items.
+//
+// Each of the M positions holds a 4-bit counter instead of a single bit: adding an item
+// increments its K counters, and deleting it decrements them, so an item added twice
+// must be deleted twice before it disappears. This takes 4 times the space of a Bloom filter
+// with the same false positive rate, but Bloom turns it into a regular filter to embed.
+//
+// A counter which reaches 15 saturates, and is never decremented again, since we can't
+// know how many items it counts anymore. With the parameters of EstimateParameters,
+// this is very unlikely to happen before the filter is well past its capacity.
+type Counting struct {
+	M uint // number of counters
+	K uint // number of hash functions
+	N uint // number of items - filter capacity
+	// DoubleHashing is true if the K positions are derived from a single digest, see positions.
+	DoubleHashing bool
+
+	hasher *hasher.Hasher
+	// counters holds M 4-bit counters, low nibble first
+	counters []byte
+	// count is the number of items added and not deleted
+	count uint
+}
+
+// NewCountingFilter creates a counting Bloom filter with capacity n and false positive rate e,
+// see EstimateParameters.
+func NewCountingFilter(n uint, e float64, opts ...Option) *Counting {
+	o := newOptions(opts)
+	m, k := EstimateParameters(n, e)
+	return &Counting{
+		M:             m,
+		K:             k,
+		N:             n,
+		DoubleHashing: o.doubleHashing,
+		hasher:        o.hasher,
+		counters:      make([]byte, (m+1)/2),
+	}
+}
+
+// EmptyCountingFilter returns a filter with no parameters, to be filled by UnmarshalBinary.
+//
+// The options are only needed to decode a filter built with a keyed hasher.
+func EmptyCountingFilter(opts ...Option) *Counting {
+	o := newOptions(opts)
+	return &Counting{hasher: o.hasher}
+}
+
+func (cf *Counting) get(p uint64) byte {
+	return cf.counters[p/2] >> (4 * (p % 2)) & 0xf
+}
+
+func (cf *Counting) set(p uint64, v byte) {
+	shift := 4 * (p % 2)
+	cf.counters[p/2] = cf.counters[p/2]&^(0xf<<shift) | v<<shift
+}
+
+func (cf *Counting) positions(input string) []uint64 {
+	return positions(cf.hasher, cf.M, cf.K, cf.DoubleHashing, input)
+}
+
+// Add inserts an item into the filter, it can be called several times for the same item.
+//
+// Like Bloom.Add, it never fails.
+func (cf *Counting) Add(input string) error {
+	for _, p := range cf.positions(input) {
+		if v := cf.get(p); v < maxCounter {
+			cf.set(p, v+1)
+		}
+	}
+	cf.count++
+	return nil
+}
+
+// Test returns true if needle may be in the filter,
+// and false if it definitely isn't.
+func (cf *Counting) Test(needle string) bool {
+	return cf.Count(needle) > 0
+}
+
+// Count returns an upper bound on the number of times needle was added and not deleted,
+// which is the smallest of its counters.
+func (cf *Counting) Count(needle string) uint {
+	min := byte(maxCounter)
+	for _, p := range cf.positions(needle) {
+		if v := cf.get(p); v < min {
+			min = v
+		}
+	}
+	return uint(min)
+}
+
+// Delete removes one occurrence of needle, and reports whether it may have been in the filter.
+//
+// Deleting an item which was never added, but is a false positive, removes
+// occurrences of other items, so only items known to be in the filter should be deleted.
+func (cf *Counting) Delete(needle string) bool {
+	ps := cf.positions(needle)
+	for _, p := range ps {
+		if cf.get(p) == 0 {
+			return false
+		}
+	}
+	for _, p := range ps {
+		if v := cf.get(p); v < maxCounter {
+			cf.set(p, v-1)
+		}
+	}
+	if cf.count > 0 {
+		cf.count--
+	}
+	return true
+}
+
+// Len returns the number of items added and not deleted.
+func (cf *Counting) Len() uint {
+	return cf.count
+}
+
+// Saturated returns the number of counters which reached their maximum value,
+// and can't be decremented anymore.
+func (cf *Counting) Saturated() uint {
+	var saturated uint
+	for p := uint64(0); p < uint64(cf.M); p++ {
+		if cf.get(p) == maxCounter {
+			saturated++
+		}
+	}
+	return saturated
+}
+
+// FalsePositiveRate returns the expected false positive rate (1 - e^(-K count / M))^K
+// given the number of items in the filter.
+func (cf *Counting) FalsePositiveRate() float64 {
+	return expectedRate(cf.M, cf.K, cf.count)
+}
+
+// Compatible returns an error wrapping ErrIncompatible if other can't be merged into cf,
+// with the same rules as Bloom.Compatible.
+func (cf *Counting) Compatible(other *Counting) error {
+	return compatible(cf.M, cf.K, cf.DoubleHashing, cf.hasher, other.M, other.K, other.DoubleHashing, other.hasher)
+}
+
+// Merge adds every item of other to cf, as if they had been added to cf directly.
+//
+// Both filters must have been created with the same parameters and hasher.
+// Counters saturate at 15, like when adding items.
+func (cf *Counting) Merge(other *Counting) error {
+	if err := cf.Compatible(other); err != nil {
+		return err
+	}
+	for p := uint64(0); p < uint64(cf.M); p++ {
+		v := cf.get(p) + other.get(p)
+		if v > maxCounter {
+			v = maxCounter
+		}
+		cf.set(p, v)
+	}
+	cf.count += other.count
+	return nil
+}
+
+// Bloom returns a regular Bloom filter with the same items, to embed where deletion isn't needed.
+//
+// Both filters give the same answer to Test, and a Bloom filter encoding is 4 times smaller.
+func (cf *Counting) Bloom() *Bloom {
+	bf := &Bloom{
+		M:             cf.M,
+		K:             cf.K,
+		N:             cf.N,
+		DoubleHashing: cf.DoubleHashing,
+		hasher:        cf.hasher,
+		bits:          make([]byte, (cf.M+7)/8),
+		count:         cf.count,
+	}
+	for p := uint64(0); p < uint64(cf.M); p++ {
+		if cf.get(p) > 0 {
+			bf.bits[p/8] |= 1 << (p % 8)
+		}
+	}
+	return bf
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+//
+// The encoding has the same header as Bloom, with a flag marking it as a counting filter.
+func (cf *Counting) MarshalBinary() ([]byte, error) {
+	if cf == nil || cf.counters == nil {
+		return nil, errors.New("bloom: nil filter")
+	}
+	out := make([]byte, 0, 3+4*binary.MaxVarintLen64+len(cf.counters))
+	out = appendHeader(out, header{
+		hasher:        cf.hasher,
+		doubleHashing: cf.DoubleHashing,
+		counting:      true,
+		m:             uint64(cf.M),
+		k:             uint64(cf.K),
+		n:             uint64(cf.N),
+		count:         uint64(cf.count),
+	})
+	return append(out, cf.counters...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+//
+// It restores a filter produced by MarshalBinary, and returns an error
+// if the data is truncated, has trailing bytes, or describes an invalid filter.
+//
+// A keyed filter can only be decoded into a Counting created by EmptyCountingFilter
+// with a hasher using the same hash function and key.
+func (cf *Counting) UnmarshalBinary(data []byte) error {
+	h, data, err := decodeHeader(cf.hasher, data)
+	if err != nil {
+		return err
+	}
+	if !h.counting {
+		return errors.New("bloom: data doesn't hold a counting filter")
+	}
+	if err := checkPacked(data, h.m, 4); err != nil {
+		return err
+	}
+
+	*cf = Counting{
+		M:             uint(h.m),
+		K:             uint(h.k),
+		N:             uint(h.n),
+		DoubleHashing: h.doubleHashing,
+		hasher:        h.hasher,
+		counters:      append([]byte{}, data...),
+		count:         uint(h.count),
+	}
+	return nil
+}
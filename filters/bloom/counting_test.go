@@ -0,0 +1,171 @@
+package bloom
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+func TestCountingDelete(t *testing.T) {
+	cf := NewCountingFilter(100, 0.001)
+	items := make([]string, 100)
+	for i := range items {
+		items[i] = fmt.Sprintf("0x%040d", i)
+		_ = cf.Add(items[i])
+	}
+	// the first item is added twice
+	_ = cf.Add(items[0])
+	if cf.Count(items[0]) < 2 {
+		t.Errorf("count of %s is %d, expected at least 2", items[0], cf.Count(items[0]))
+	}
+
+	for _, item := range items[1:50] {
+		if !cf.Delete(item) {
+			t.Errorf("%s could not be deleted", item)
+		}
+	}
+	if cf.Len() != 52 {
+		t.Errorf("length is %d, expected 52", cf.Len())
+	}
+	for _, item := range items[50:] {
+		if !cf.Test(item) {
+			t.Errorf("%s missing after deleting other items", item)
+		}
+	}
+
+	// an item added twice survives a single deletion
+	if !cf.Delete(items[0]) || !cf.Test(items[0]) {
+		t.Errorf("%s should still be present after one deletion", items[0])
+	}
+	if !cf.Delete(items[0]) {
+		t.Errorf("%s could not be deleted twice", items[0])
+	}
+
+	if cf.Delete("never added") {
+		t.Error("deleting a missing item should report false")
+	}
+}
+
+func TestCountingSaturation(t *testing.T) {
+	cf := NewCountingFilter(10, 0.01)
+	for i := 0; i < maxCounter+5; i++ {
+		_ = cf.Add("hello")
+	}
+	if cf.Saturated() != cf.K {
+		t.Fatalf("%d saturated counters, expected %d", cf.Saturated(), cf.K)
+	}
+	for i := 0; i < maxCounter+5; i++ {
+		cf.Delete("hello")
+	}
+	// saturated counters are never decremented, so we can't lose other items
+	if !cf.Test("hello") {
+		t.Error("saturated counters were decremented")
+	}
+}
+
+func TestCountingMerge(t *testing.T) {
+	a := NewCountingFilter(100, 0.01)
+	b := NewCountingFilter(100, 0.01)
+	for i := 0; i < 50; i++ {
+		_ = a.Add(fmt.Sprint(i))
+		_ = b.Add(fmt.Sprint(i + 50))
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if a.Len() != 100 {
+		t.Errorf("length is %d, expected 100", a.Len())
+	}
+	for i := 0; i < 100; i++ {
+		if !a.Test(fmt.Sprint(i)) {
+			t.Errorf("%d missing after merge", i)
+		}
+	}
+	// items coming from b can be deleted from the merged filter
+	if !a.Delete("75") {
+		t.Error("75 could not be deleted after merge")
+	}
+
+	key := hasher.DeriveKey("counting test", []byte("wallet secret"))
+	keyed, _ := hasher.New(hasher.SHA1, key)
+	incompatible := []*Counting{
+		NewCountingFilter(1000, 0.01),
+		NewCountingFilter(100, 0.01, WithDoubleHashing()),
+		NewCountingFilter(100, 0.01, WithHasher(keyed)),
+	}
+	for _, other := range incompatible {
+		if err := a.Merge(other); err == nil {
+			t.Errorf("merging with %+v should fail", other)
+		}
+	}
+}
+
+func TestCountingBloom(t *testing.T) {
+	cf := NewCountingFilter(100, 0.01)
+	for i := 0; i < 100; i++ {
+		_ = cf.Add(fmt.Sprint(i))
+	}
+	cf.Delete("0")
+
+	bf := cf.Bloom()
+	expected := NewBloomFilter(100, 0.01)
+	for i := 1; i < 100; i++ {
+		_ = expected.Add(fmt.Sprint(i))
+	}
+	for i := 0; i < 1000; i++ {
+		if bf.Test(fmt.Sprint(i)) != cf.Test(fmt.Sprint(i)) {
+			t.Fatalf("filters disagree on %d", i)
+		}
+	}
+	// without saturated counters, the bits are the same as if 0 had never been added
+	if !bytes.Equal(bf.bits, expected.bits) {
+		t.Error("bits differ from a Bloom filter with the same items")
+	}
+}
+
+func TestCountingMarshal(t *testing.T) {
+	cf := NewCountingFilter(10, 0.01)
+	for i := 0; i < 10; i++ {
+		_ = cf.Add(fmt.Sprint(i))
+	}
+	data, err := cf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cf2 := new(Counting)
+	if err := cf2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if cf2.M != cf.M || cf2.K != cf.K || cf2.Len() != cf.Len() {
+		t.Fatalf("parameters differ after round trip: %+v", cf2)
+	}
+	for i := 0; i < 10; i++ {
+		if cf2.Count(fmt.Sprint(i)) != cf.Count(fmt.Sprint(i)) {
+			t.Errorf("count of %d differs after round trip", i)
+		}
+	}
+	data2, _ := cf2.MarshalBinary()
+	if !bytes.Equal(data, data2) {
+		t.Error("encoding is not stable across a round trip")
+	}
+
+	// the two kinds of filters can't be mixed up
+	if err := new(Bloom).UnmarshalBinary(data); err == nil {
+		t.Error("decoding a counting filter as a Bloom filter should fail")
+	}
+	bloomData, _ := cf.Bloom().MarshalBinary()
+	if err := new(Counting).UnmarshalBinary(bloomData); err == nil {
+		t.Error("decoding a Bloom filter as a counting filter should fail")
+	}
+
+	// 96 counters, so the last byte has no padding: truncate and extend instead
+	if err := new(Counting).UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("decoding truncated data should fail")
+	}
+	if err := new(Counting).UnmarshalBinary(append(data, 0)); err == nil {
+		t.Error("decoding data with trailing bytes should fail")
+	}
+}
@@ -0,0 +1,64 @@
+package bloom
+
+import (
+	"math"
+
+	"github.com/taurusgroup/multi-party-sig/filters"
+)
+
+func init() {
+	filters.Register("bloom", func(n uint, e float64) filters.Filter {
+		return NewBloomFilter(n, e)
+	})
+	filters.RegisterEstimator("bloom", Estimate)
+	filters.RegisterDecoder("bloom", func(data []byte) (filters.Filter, error) {
+		bf := EmptyBloomFilter()
+		return bf, bf.UnmarshalBinary(data)
+	})
+	filters.Register("counting-bloom", func(n uint, e float64) filters.Filter {
+		return NewCountingFilter(n, e)
+	})
+	filters.RegisterEstimator("counting-bloom", EstimateCounting)
+	filters.RegisterDecoder("counting-bloom", func(data []byte) (filters.Filter, error) {
+		cf := EmptyCountingFilter()
+		return cf, cf.UnmarshalBinary(data)
+	})
+}
+
+var (
+	_ filters.Filter  = (*Bloom)(nil)
+	_ filters.Deleter = (*Counting)(nil)
+)
+
+// Len implements filters.Filter, it is the same as Count.
+func (bf *Bloom) Len() uint {
+	return bf.count
+}
+
+// Estimate returns the encoded size and false positive rate of NewBloomFilter(n, e)
+// holding n items.
+func Estimate(n uint, e float64) (size int, rate float64) {
+	m, k := EstimateParameters(n, e)
+	return headerSize(m, k, n) + int((m+7)/8), expectedRate(m, k, n)
+}
+
+// EstimateCounting returns the encoded size and false positive rate of NewCountingFilter(n, e)
+// holding n items.
+func EstimateCounting(n uint, e float64) (size int, rate float64) {
+	m, k := EstimateParameters(n, e)
+	return headerSize(m, k, n) + int((m+1)/2), expectedRate(m, k, n)
+}
+
+// headerSize returns the size of the header of a filter holding its capacity n.
+func headerSize(m, k, n uint) int {
+	size := 3
+	for _, v := range []uint{m, k, n, n} {
+		size += len(appendUvarint(nil, uint64(v)))
+	}
+	return size
+}
+
+// expectedRate returns the false positive rate (1 - e^(-k n / m))^k.
+func expectedRate(m, k, n uint) float64 {
+	return math.Pow(1-math.Exp(-float64(k)*float64(n)/float64(m)), float64(k))
+}
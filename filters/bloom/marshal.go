@@ -0,0 +1,200 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+// Binary layout produced by MarshalBinary:
+//
+//	version  (1 byte)
+//	hash id  (1 byte, see hasher.ID)
+//	flags    (1 byte, bit 0 set for double hashing, bit 1 for a keyed hash, bit 2 for counters)
+//	M, K, N, count (unsigned varints)
+//	bits     (ceil(M/8) bytes, least significant bit first, padding bits unset)
+//
+// Counting filters store ceil(M/2) bytes of 4-bit counters instead of bits,
+// low nibble first, see Counting.
+//
+// The encoding only depends on the parameters, the set of items added, and count,
+// which is the number of calls to Add, duplicates included. Two filters built from the same
+// distinct items, as filters.Build does, always serialize to the same bytes.
+const (
+	// encodingVersion is bumped whenever the layout above changes.
+	encodingVersion byte = 1
+
+	// flagDoubleHashing is set in the flags byte when positions are derived with double hashing.
+	flagDoubleHashing byte = 1 << 0
+	// flagKeyed is set in the flags byte when the hash is keyed.
+	// The key itself is never encoded.
+	flagKeyed byte = 1 << 1
+	// flagCounting is set in the flags byte for a Counting filter.
+	flagCounting byte = 1 << 2
+)
+
+// header holds the fields shared by the encodings of Bloom and Counting.
+type header struct {
+	hasher         *hasher.Hasher
+	doubleHashing  bool
+	counting       bool
+	m, k, n, count uint64
+}
+
+func appendHeader(out []byte, h header) []byte {
+	var flags byte
+	if h.doubleHashing {
+		flags |= flagDoubleHashing
+	}
+	if h.hasher.Keyed() {
+		flags |= flagKeyed
+	}
+	if h.counting {
+		flags |= flagCounting
+	}
+	out = append(out, encodingVersion, byte(h.hasher.ID()), flags)
+	for _, v := range []uint64{h.m, h.k, h.n, h.count} {
+		out = appendUvarint(out, v)
+	}
+	return out
+}
+
+// decodeHeader parses and validates the header of an encoded filter,
+// and returns it along with the remaining data.
+func decodeHeader(current *hasher.Hasher, data []byte) (header, []byte, error) {
+	var h header
+	if len(data) < 3 {
+		return h, nil, errors.New("bloom: data too short")
+	}
+	if data[0] != encodingVersion {
+		return h, nil, fmt.Errorf("bloom: unsupported encoding version %d", data[0])
+	}
+	flags := data[2]
+	if flags&^(flagDoubleHashing|flagKeyed|flagCounting) != 0 {
+		return h, nil, fmt.Errorf("bloom: unknown flags %#x", flags)
+	}
+	h.doubleHashing = flags&flagDoubleHashing != 0
+	h.counting = flags&flagCounting != 0
+
+	var err error
+	h.hasher, err = decodeHasher(current, hasher.ID(data[1]), flags&flagKeyed != 0)
+	if err != nil {
+		return h, nil, err
+	}
+	data = data[3:]
+
+	for _, v := range []*uint64{&h.m, &h.k, &h.n, &h.count} {
+		var n int
+		*v, n = binary.Uvarint(data)
+		if n <= 0 {
+			return h, nil, errors.New("bloom: invalid header")
+		}
+		data = data[n:]
+	}
+
+	if h.m == 0 {
+		return h, nil, errors.New("bloom: filter has no bits")
+	}
+	if h.k == 0 || h.k > maxHashes {
+		return h, nil, fmt.Errorf("bloom: invalid number of hash functions %d", h.k)
+	}
+	return h, data, nil
+}
+
+// checkPacked checks that data holds exactly m values of width bits (1 or 4),
+// and that the padding bits are unset.
+func checkPacked(data []byte, m, width uint64) error {
+	perByte := 8 / width
+	// compare without rounding m up, which could overflow for a forged header
+	full, partial := m/perByte, uint64(0)
+	if m%perByte != 0 {
+		partial = 1
+	}
+	if uint64(len(data)) < full || uint64(len(data))-full != partial {
+		return fmt.Errorf("bloom: expected %d values, found %d bytes", m, len(data))
+	}
+	if used := (m % perByte) * width; used != 0 && data[len(data)-1]>>used != 0 {
+		return errors.New("bloom: invalid padding")
+	}
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+//
+// The result is a compact, versioned encoding of the filter which can be
+// restored with UnmarshalBinary.
+func (bf *Bloom) MarshalBinary() ([]byte, error) {
+	if bf == nil || bf.bits == nil {
+		return nil, errors.New("bloom: nil filter")
+	}
+	out := make([]byte, 0, 3+4*binary.MaxVarintLen64+len(bf.bits))
+	out = appendHeader(out, header{
+		hasher:        bf.hasher,
+		doubleHashing: bf.DoubleHashing,
+		m:             uint64(bf.M),
+		k:             uint64(bf.K),
+		n:             uint64(bf.N),
+		count:         uint64(bf.count),
+	})
+	return append(out, bf.bits...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+//
+// It restores a filter produced by MarshalBinary, and returns an error
+// if the data is truncated, has trailing bytes, or describes an invalid filter.
+//
+// A keyed filter can only be decoded into a Bloom created by EmptyBloomFilter
+// with a hasher using the same hash function and key.
+func (bf *Bloom) UnmarshalBinary(data []byte) error {
+	h, data, err := decodeHeader(bf.hasher, data)
+	if err != nil {
+		return err
+	}
+	if h.counting {
+		return errors.New("bloom: data holds a counting filter")
+	}
+	if err := checkPacked(data, h.m, 1); err != nil {
+		return err
+	}
+
+	*bf = Bloom{
+		M:             uint(h.m),
+		K:             uint(h.k),
+		N:             uint(h.n),
+		DoubleHashing: h.doubleHashing,
+		hasher:        h.hasher,
+		bits:          append([]byte{}, data...),
+		count:         uint(h.count),
+	}
+	return nil
+}
+
+// decodeHasher returns the hasher to use for a decoded filter.
+//
+// Unkeyed hashers are fully described by their id, but keyed ones must be provided
+// beforehand as current, since we don't know the key.
+func decodeHasher(current *hasher.Hasher, id hasher.ID, keyed bool) (*hasher.Hasher, error) {
+	if !keyed {
+		h, err := hasher.New(id, nil)
+		if err != nil {
+			return nil, fmt.Errorf("bloom: %w", err)
+		}
+		return h, nil
+	}
+	if current == nil || !current.Keyed() {
+		return nil, errors.New("bloom: filter uses a keyed hash, decode it with EmptyBloomFilter(WithHasher(h))")
+	}
+	if current.ID() != id {
+		return nil, fmt.Errorf("bloom: filter uses %s, but hasher is %s", id, current.ID())
+	}
+	return current, nil
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
@@ -0,0 +1,149 @@
+package bloom
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	for _, doubleHashing := range []bool{false, true} {
+		var opts []Option
+		if doubleHashing {
+			opts = append(opts, WithDoubleHashing())
+		}
+		bf := NewBloomFilter(10, 0.001, opts...)
+		items := make([]string, 10)
+		for i := range items {
+			items[i] = fmt.Sprintf("0x%040d", i)
+			_ = bf.Add(items[i])
+		}
+
+		data, err := bf.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bf2 := new(Bloom)
+		if err := bf2.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if bf2.M != bf.M || bf2.K != bf.K || bf2.N != bf.N || bf2.DoubleHashing != doubleHashing {
+			t.Fatalf("parameters differ after round trip: %+v", bf2)
+		}
+		if bf2.Count() != bf.Count() {
+			t.Errorf("count is %d after round trip, expected %d", bf2.Count(), bf.Count())
+		}
+		for _, item := range items {
+			if !bf2.Test(item) {
+				t.Errorf("%s missing after round trip", item)
+			}
+		}
+
+		data2, err := bf2.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, data2) {
+			t.Error("encoding is not stable across a round trip")
+		}
+	}
+}
+
+func TestMarshalDeterministic(t *testing.T) {
+	a := NewBloomFilter(10, 0.01)
+	b := NewBloomFilter(10, 0.01)
+	for i := 0; i < 10; i++ {
+		_ = a.Add(fmt.Sprint(i))
+		_ = b.Add(fmt.Sprint(9 - i))
+	}
+	dataA, _ := a.MarshalBinary()
+	dataB, _ := b.MarshalBinary()
+	if !bytes.Equal(dataA, dataB) {
+		t.Error("insertion order changed the encoding")
+	}
+}
+
+func TestMarshalSize(t *testing.T) {
+	bf := NewBloomFilter(10, 0.001)
+	data, _ := bf.MarshalBinary()
+	// 144 bits, header of 3 bytes + varints 144 (2 bytes), 10, 10, 0
+	if got, want := len(data), 3+5+18; got != want {
+		t.Errorf("encoding is %d bytes, expected %d", got, want)
+	}
+}
+
+func TestUnmarshalCorrupt(t *testing.T) {
+	bf := NewBloomFilter(3, 0.01)
+	_ = bf.Add("hello")
+	_ = bf.Add("world")
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withByte := func(i int, b byte) []byte {
+		out := append([]byte{}, data...)
+		out[i] = b
+		return out
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"nil", nil},
+		{"version", withByte(0, encodingVersion+1)},
+		{"hash id", withByte(1, 0xff)},
+		{"flags", withByte(2, 0x80)},
+		{"header only", data[:4]},
+		{"truncated", data[:len(data)-1]},
+		{"trailing", append(append([]byte{}, data...), 0)},
+		{"no bits", withByte(3, 0)},
+		{"zero hashes", withByte(4, 0)},
+		{"too many hashes", withByte(4, maxHashes+1)},
+		// 29 bits, the last 3 bits of the last byte are padding
+		{"padding", withByte(len(data)-1, 0x80)},
+		{"huge filter", []byte{encodingVersion, byte(hasher.SHA1), 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 7, 3, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := new(Bloom).UnmarshalBinary(tt.data); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestMarshalKeyed(t *testing.T) {
+	key := hasher.DeriveKey("bloom test", []byte("wallet secret"))
+	h, err := hasher.New(hasher.BLAKE3, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf := NewBloomFilter(10, 0.001, WithHasher(h))
+	_ = bf.Add("hello")
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without the key, the filter can't be decoded
+	if err := new(Bloom).UnmarshalBinary(data); err == nil {
+		t.Error("decoding a keyed filter without its key should fail")
+	}
+	other, _ := hasher.New(hasher.SHA256, key)
+	if err := EmptyBloomFilter(WithHasher(other)).UnmarshalBinary(data); err == nil {
+		t.Error("decoding a keyed filter with the wrong hash should fail")
+	}
+
+	bf2 := EmptyBloomFilter(WithHasher(h))
+	if err := bf2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !bf2.Test("hello") {
+		t.Error("hello missing after round trip")
+	}
+}
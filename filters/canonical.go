@@ -0,0 +1,85 @@
+package filters
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/taurusgroup/multi-party-sig/pkg/hash"
+)
+
+// Seeder is implemented by filters whose construction involves random choices,
+// such as the entry a cuckoo filter evicts when both buckets of an item are full.
+//
+// Seeding a filter makes these choices a deterministic function of seed,
+// so that adding the same items in the same order always gives the same filter.
+type Seeder interface {
+	Filter
+	// Seed replaces the randomness used by later insertions with a stream derived from seed.
+	Seed(seed []byte)
+}
+
+// Canonical returns the distinct items, sorted in increasing byte order.
+//
+// items is not modified.
+func Canonical(items []string) []string {
+	sorted := append([]string{}, items...)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for i, item := range sorted {
+		if i == 0 || item != sorted[i-1] {
+			unique = append(unique, item)
+		}
+	}
+	return unique
+}
+
+// SetDigest returns a 64 byte digest of the set of items,
+// which doesn't depend on their order or on duplicates.
+func SetDigest(items []string) []byte {
+	h := hash.New()
+	for _, item := range Canonical(items) {
+		// items are length prefixed by WriteAny, so the set can't be split differently
+		_ = h.WriteAny(hash.BytesWithDomain{TheDomain: "Filter Item", Bytes: []byte(item)})
+	}
+	return h.Sum()
+}
+
+// Build creates the filter registered as name, with false positive rate e, and adds items to it
+// in canonical order.
+//
+// Filters implementing Seeder are seeded with SetDigest(items) beforehand, so that
+// everyone building a filter of the same type from the same set of items, in whatever order,
+// obtains the same encoding, and therefore the same Digest.
+func Build(name string, items []string, e float64) (Filter, error) {
+	items = Canonical(items)
+	f, err := New(name, uint(len(items)), e)
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := f.(Seeder); ok {
+		s.Seed(SetDigest(items))
+	}
+	for _, item := range items {
+		if err := f.Add(item); err != nil {
+			return nil, fmt.Errorf("filters: build %s: %w", name, err)
+		}
+	}
+	return f, nil
+}
+
+// Digest returns a 64 byte digest of the encoding of f,
+// which parties can commit to, or sign along with a message.
+func Digest(f Filter) ([]byte, error) {
+	data, err := f.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("filters: %w", err)
+	}
+	return DigestBytes(data), nil
+}
+
+// DigestBytes returns the digest of a filter encoded as data, see Digest.
+func DigestBytes(data []byte) []byte {
+	h := hash.New()
+	_ = h.WriteAny(hash.BytesWithDomain{TheDomain: "Filter", Bytes: append([]byte{}, data...)})
+	return h.Sum()
+}
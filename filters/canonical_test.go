@@ -0,0 +1,66 @@
+package filters_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/taurusgroup/multi-party-sig/filters"
+)
+
+func TestCanonical(t *testing.T) {
+	items := []string{"b", "a", "c", "a", "b"}
+	if got := filters.Canonical(items); fmt.Sprint(got) != "[a b c]" {
+		t.Errorf("canonical form is %v", got)
+	}
+	if fmt.Sprint(items) != "[b a c a b]" {
+		t.Errorf("input was modified: %v", items)
+	}
+
+	if !bytes.Equal(filters.SetDigest(items), filters.SetDigest([]string{"c", "b", "a"})) {
+		t.Error("digest depends on the order of items")
+	}
+	if bytes.Equal(filters.SetDigest([]string{"ab", "c"}), filters.SetDigest([]string{"a", "bc"})) {
+		t.Error("different sets have the same digest")
+	}
+}
+
+func TestBuildDeterministic(t *testing.T) {
+	items := make([]string, 950)
+	for i := range items {
+		items[i] = fmt.Sprintf("item-%d", i)
+	}
+	shuffled := append([]string{}, items...)
+	rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	shuffled = append(shuffled, items[:10]...)
+
+	for _, name := range filters.Names() {
+		f1, err := filters.Build(name, items, 0.001)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		f2, err := filters.Build(name, shuffled, 0.001)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		data1, _ := f1.MarshalBinary()
+		data2, _ := f2.MarshalBinary()
+		if !bytes.Equal(data1, data2) {
+			t.Errorf("%s: encodings differ", name)
+		}
+		d1, err := filters.Digest(f1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d2, _ := filters.Digest(f2)
+		if !bytes.Equal(d1, d2) {
+			t.Errorf("%s: digests differ", name)
+		}
+		for _, item := range items {
+			if !f1.Test(item) {
+				t.Fatalf("%s: %s missing", name, item)
+			}
+		}
+	}
+}
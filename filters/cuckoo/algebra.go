@@ -0,0 +1,143 @@
+package cuckoo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrIncompatible is returned when combining filters with different parameters or hashers.
+var ErrIncompatible = errors.New("cuckoo: filters are not compatible")
+
+// Compatible returns an error wrapping ErrIncompatible if other can't be combined with c.
+//
+// Both filters must have the same number of buckets, bucket size and fingerprint length,
+// and use the same hash function and key, so that an item has the same fingerprint and
+// candidate buckets in both. Semi-sorting only changes how buckets are stored, so it may differ.
+func (c *Cuckoo) Compatible(other *Cuckoo) error {
+	switch {
+	case c.M != other.M || c.B != other.B:
+		return fmt.Errorf("%w: %d buckets of %d entries, and %d of %d", ErrIncompatible, c.M, c.B, other.M, other.B)
+	case c.F != other.F:
+		return fmt.Errorf("%w: fingerprints of %d and %d bits", ErrIncompatible, c.F, other.F)
+	case !c.hasher.Equal(other.hasher):
+		return fmt.Errorf("%w: different hash functions or keys", ErrIncompatible)
+	}
+	return nil
+}
+
+// clone returns a deep copy of c.
+func (c *Cuckoo) clone() *Cuckoo {
+	c2 := *c
+	t := *c.table
+	t.data = append([]byte{}, c.table.data...)
+	c2.table = &t
+	return &c2
+}
+
+// entries calls f for every fingerprint stored in c, along with its bucket.
+func (c *Cuckoo) entries(f func(i uint, tag Fingerprint)) {
+	var buf [maxBucketSize]Fingerprint
+	tags := buf[:c.B]
+	for i := uint(0); i < c.M; i++ {
+		c.table.read(i, tags)
+		for _, tag := range tags {
+			if tag != 0 {
+				f(i, tag)
+			}
+		}
+	}
+}
+
+// add stores a fingerprint found in bucket i of a compatible filter.
+func (c *Cuckoo) add(i uint, f Fingerprint) bool {
+	if c.insertInto(i, f) || c.insertInto(c.altIndex(i, f), f) || c.relocate(i, f) {
+		c.count++
+		return true
+	}
+	return false
+}
+
+// has reports whether a fingerprint found in bucket i of a compatible filter is in c.
+func (c *Cuckoo) has(i uint, f Fingerprint) bool {
+	return c.contains(i, f) || c.contains(c.altIndex(i, f), f)
+}
+
+// combine applies op to a copy of c, and only replaces c with it if op succeeds,
+// so that a failed operation leaves c untouched.
+func (c *Cuckoo) combine(other *Cuckoo, op func(c2 *Cuckoo) error) error {
+	if err := c.Compatible(other); err != nil {
+		return err
+	}
+	c2 := c.clone()
+	if err := op(c2); err != nil {
+		return err
+	}
+	*c = *c2
+	return nil
+}
+
+// Merge adds every entry of other to c, keeping duplicates:
+// an item present in both filters is stored twice, and must be deleted twice.
+//
+// It returns ErrFilterFull, and leaves c untouched, if the entries don't fit.
+func (c *Cuckoo) Merge(other *Cuckoo) error {
+	return c.combine(other, func(c2 *Cuckoo) error {
+		var err error
+		other.entries(func(i uint, f Fingerprint) {
+			if err == nil && !c2.add(i, f) {
+				err = ErrFilterFull
+			}
+		})
+		return err
+	})
+}
+
+// Union adds the entries of other which are not already in c.
+//
+// Unlike Merge, an item present in both filters is only stored once,
+// which is what we want when every party's filter holds the same signers.
+// It returns ErrFilterFull, and leaves c untouched, if the entries don't fit.
+func (c *Cuckoo) Union(other *Cuckoo) error {
+	return c.combine(other, func(c2 *Cuckoo) error {
+		var err error
+		other.entries(func(i uint, f Fingerprint) {
+			if err == nil && !c2.has(i, f) && !c2.add(i, f) {
+				err = ErrFilterFull
+			}
+		})
+		return err
+	})
+}
+
+// Intersect removes from c the entries which are not in other.
+//
+// Like any filter intersection, the result may hold entries of items which are in only one
+// of the filters, when they share their fingerprint and buckets with an item of the other.
+func (c *Cuckoo) Intersect(other *Cuckoo) error {
+	return c.combine(other, func(c2 *Cuckoo) error {
+		c.entries(func(i uint, f Fingerprint) {
+			if !other.has(i, f) {
+				c2.remove(i, f)
+				c2.count--
+			}
+		})
+		return nil
+	})
+}
+
+// Union returns a new filter holding the union of filters, which must be compatible.
+//
+// This is how a coordinator combines the filters built by every party of a session
+// into a single group filter. None of the filters are modified.
+func Union(filters ...*Cuckoo) (*Cuckoo, error) {
+	if len(filters) == 0 {
+		return nil, errors.New("cuckoo: no filters to combine")
+	}
+	out := filters[0].clone()
+	for _, f := range filters[1:] {
+		if err := out.Union(f); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,135 @@
+package cuckoo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+func TestUnion(t *testing.T) {
+	// every party's filter holds its own items, and the items of every party
+	parties := make([]*Cuckoo, 3)
+	for p := range parties {
+		parties[p] = NewCuckooFilter(400, 0.001)
+		for i := 0; i < 100; i++ {
+			_ = parties[p].Insert(fmt.Sprint("party", p, "-", i))
+			_ = parties[p].Insert(fmt.Sprint("shared-", i))
+		}
+	}
+
+	group, err := Union(parties...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// shared items are only stored once
+	if group.Count() != 400 {
+		t.Errorf("count is %d, expected 400", group.Count())
+	}
+	for p := range parties {
+		for i := 0; i < 100; i++ {
+			if !group.Lookup(fmt.Sprint("party", p, "-", i)) {
+				t.Errorf("item %d of party %d missing", i, p)
+			}
+		}
+	}
+	if parties[0].Count() != 200 {
+		t.Error("union modified its inputs")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := NewCuckooFilter(100, 0.01)
+	b := NewCuckooFilter(100, 0.01)
+	_ = a.Insert("hello")
+	_ = b.Insert("hello")
+	_ = b.Insert("world")
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if a.Count() != 3 {
+		t.Errorf("count is %d, expected 3", a.Count())
+	}
+	// hello was in both filters, so it takes two deletions to remove it
+	a.Delete("hello")
+	if !a.Lookup("hello") {
+		t.Error("hello should still be present after one deletion")
+	}
+	a.Delete("hello")
+	if a.Lookup("hello") {
+		t.Error("hello still present after two deletions")
+	}
+}
+
+func TestMergeFull(t *testing.T) {
+	a := NewCuckooFilter(8, 0.01)
+	b := NewCuckooFilter(8, 0.01)
+	for i := 0; a.Insert(fmt.Sprint("a", i)) == nil; i++ {
+	}
+	for i := 0; i < 4; i++ {
+		_ = b.Insert(fmt.Sprint("b", i))
+	}
+
+	before, _ := a.MarshalBinary()
+	if err := a.Merge(b); err != ErrFilterFull {
+		t.Fatalf("expected ErrFilterFull, got %v", err)
+	}
+	after, _ := a.MarshalBinary()
+	if string(before) != string(after) {
+		t.Error("a failed merge modified the filter")
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	a := NewCuckooFilter(100, 0.001)
+	b := NewCuckooFilter(100, 0.001)
+	for i := 0; i < 100; i++ {
+		_ = a.Insert(fmt.Sprint(i))
+		_ = b.Insert(fmt.Sprint(i + 50))
+	}
+	if err := a.Intersect(b); err != nil {
+		t.Fatal(err)
+	}
+	for i := 50; i < 100; i++ {
+		if !a.Lookup(fmt.Sprint(i)) {
+			t.Errorf("%d missing from the intersection", i)
+		}
+	}
+	if a.Count() < 50 || a.Count() > 52 {
+		t.Errorf("count is %d, expected about 50", a.Count())
+	}
+}
+
+func TestIncompatible(t *testing.T) {
+	keyed, _ := hasher.New(hasher.SHA1, hasher.DeriveKey("algebra test", []byte("wallet secret")))
+
+	c := NewCuckooFilter(100, 0.01)
+	incompatible := []*Cuckoo{
+		NewCuckooFilter(1000, 0.01),
+		NewCuckooFilter(100, 0.001),
+		NewCuckooFilter(100, 0.01, WithHasher(keyed)),
+	}
+	for _, other := range incompatible {
+		if err := c.Union(other); !errors.Is(err, ErrIncompatible) {
+			t.Errorf("union: expected ErrIncompatible, got %v", err)
+		}
+		if err := c.Merge(other); !errors.Is(err, ErrIncompatible) {
+			t.Errorf("merge: expected ErrIncompatible, got %v", err)
+		}
+		if err := c.Intersect(other); !errors.Is(err, ErrIncompatible) {
+			t.Errorf("intersection: expected ErrIncompatible, got %v", err)
+		}
+	}
+
+	// semi-sorting doesn't change which items match
+	semi := NewCuckooFilter(100, 0.01, WithSemiSorting())
+	_ = semi.Insert("hello")
+	if err := c.Union(semi); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Lookup("hello") {
+		t.Error("hello missing after union with a semi-sorted filter")
+	}
+}
@@ -0,0 +1,160 @@
+package cuckoo
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// maxStripes bounds the number of locks used by a ConcurrentCuckoo.
+const maxStripes = 64
+
+// ConcurrentCuckoo is a cuckoo filter which can be shared between goroutines.
+//
+// Buckets are guarded by a set of striped locks: Lookup, Delete and the common case of
+// Insert only lock the stripes covering the item's two candidate buckets, so operations
+// on different items rarely contend. When an insertion needs to relocate entries,
+// it takes every stripe, since the relocation path may touch any bucket.
+type ConcurrentCuckoo struct {
+	// count is accessed atomically, and kept first for 64-bit alignment.
+	count  uint64
+	filter *Cuckoo
+	// group is the number of consecutive buckets covered by a single stripe.
+	// Packed buckets may share a byte with their neighbours, so stripes
+	// must cover whole bytes of the table.
+	group   uint
+	stripes []sync.RWMutex
+}
+
+// NewConcurrentCuckooFilter creates a ConcurrentCuckoo with capacity n and false positive rate e.
+//
+// The parameters are chosen exactly like NewCuckooFilter.
+func NewConcurrentCuckooFilter(n uint, e float64, opts ...Option) *ConcurrentCuckoo {
+	c := NewCuckooFilter(n, e, opts...)
+
+	// the smallest number of buckets whose bits add up to a whole number of bytes
+	group := uint(1)
+	for (group*c.table.bucketBits)%8 != 0 {
+		group <<= 1
+	}
+
+	stripes := (c.M + group - 1) / group
+	if stripes > maxStripes {
+		stripes = maxStripes
+	}
+	return &ConcurrentCuckoo{
+		filter:  c,
+		group:   group,
+		stripes: make([]sync.RWMutex, stripes),
+	}
+}
+
+// stripesFor returns the, deduplicated and ordered, stripe indices covering buckets i1 and i2.
+//
+// Locks are always taken in increasing order, which prevents deadlocks.
+func (cc *ConcurrentCuckoo) stripesFor(i1, i2 uint) []uint {
+	n := uint(len(cc.stripes))
+	s1, s2 := (i1/cc.group)%n, (i2/cc.group)%n
+	switch {
+	case s1 == s2:
+		return []uint{s1}
+	case s1 < s2:
+		return []uint{s1, s2}
+	default:
+		return []uint{s2, s1}
+	}
+}
+
+func (cc *ConcurrentCuckoo) lockAll() {
+	for i := range cc.stripes {
+		cc.stripes[i].Lock()
+	}
+}
+
+func (cc *ConcurrentCuckoo) unlockAll() {
+	for i := len(cc.stripes) - 1; i >= 0; i-- {
+		cc.stripes[i].Unlock()
+	}
+}
+
+// Insert adds an item to the filter.
+//
+// Like Cuckoo.Insert, it returns ErrFilterFull and leaves the filter untouched
+// if the item doesn't fit.
+func (cc *ConcurrentCuckoo) Insert(input string) error {
+	i1, i2, f := cc.filter.hashes(input)
+
+	stripes := cc.stripesFor(i1, i2)
+	for _, s := range stripes {
+		cc.stripes[s].Lock()
+	}
+	ok := cc.filter.insertInto(i1, f) || cc.filter.insertInto(i2, f)
+	for i := len(stripes) - 1; i >= 0; i-- {
+		cc.stripes[stripes[i]].Unlock()
+	}
+	if ok {
+		atomic.AddUint64(&cc.count, 1)
+		return nil
+	}
+
+	// Both buckets were full, we need exclusive access to move entries around.
+	// Another goroutine may have freed a slot in the meantime, so we try again first.
+	cc.lockAll()
+	defer cc.unlockAll()
+	if !cc.filter.insertInto(i1, f) && !cc.filter.insertInto(i2, f) && !cc.filter.relocate(i1, f) {
+		return ErrFilterFull
+	}
+	atomic.AddUint64(&cc.count, 1)
+	return nil
+}
+
+// Lookup returns true if needle may be in the filter.
+func (cc *ConcurrentCuckoo) Lookup(needle string) bool {
+	c := cc.filter
+	i1, i2, f := c.hashes(needle)
+
+	stripes := cc.stripesFor(i1, i2)
+	for _, s := range stripes {
+		cc.stripes[s].RLock()
+	}
+	defer func() {
+		for i := len(stripes) - 1; i >= 0; i-- {
+			cc.stripes[stripes[i]].RUnlock()
+		}
+	}()
+
+	return c.contains(i1, f) || c.contains(i2, f)
+}
+
+// Delete removes one occurrence of needle, and reports whether a matching fingerprint was found.
+func (cc *ConcurrentCuckoo) Delete(needle string) bool {
+	c := cc.filter
+	i1, i2, f := c.hashes(needle)
+
+	stripes := cc.stripesFor(i1, i2)
+	for _, s := range stripes {
+		cc.stripes[s].Lock()
+	}
+	defer func() {
+		for i := len(stripes) - 1; i >= 0; i-- {
+			cc.stripes[stripes[i]].Unlock()
+		}
+	}()
+
+	if c.remove(i1, f) || c.remove(i2, f) {
+		atomic.AddUint64(&cc.count, ^uint64(0))
+		return true
+	}
+	return false
+}
+
+// Count returns the number of fingerprints currently stored in the filter.
+func (cc *ConcurrentCuckoo) Count() uint {
+	return uint(atomic.LoadUint64(&cc.count))
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, using the same encoding as Cuckoo.
+func (cc *ConcurrentCuckoo) MarshalBinary() ([]byte, error) {
+	cc.lockAll()
+	defer cc.unlockAll()
+	return cc.filter.MarshalBinary()
+}
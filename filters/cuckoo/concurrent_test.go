@@ -0,0 +1,107 @@
+package cuckoo
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// These tests are most useful when run with the race detector:
+//
+//	go test -race ./...
+
+func TestConcurrentInsertLookup(t *testing.T) {
+	const (
+		workers = 8
+		items   = 200
+	)
+	cc := NewConcurrentCuckooFilter(workers*items, 0.001)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < items; i++ {
+				item := fmt.Sprintf("%d-%d", w, i)
+				if err := cc.Insert(item); err != nil {
+					t.Error(err)
+					return
+				}
+				if !cc.Lookup(item) {
+					t.Errorf("%s missing right after insertion", item)
+				}
+				// look at another worker's items while they're being written
+				cc.Lookup(fmt.Sprintf("%d-%d", (w+1)%workers, i))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if cc.Count() != workers*items {
+		t.Errorf("count is %d, expected %d", cc.Count(), workers*items)
+	}
+	for w := 0; w < workers; w++ {
+		for i := 0; i < items; i++ {
+			if !cc.Lookup(fmt.Sprintf("%d-%d", w, i)) {
+				t.Errorf("%d-%d missing", w, i)
+			}
+		}
+	}
+}
+
+func TestConcurrentInsertDelete(t *testing.T) {
+	const workers = 8
+	// a small filter, so that insertions regularly go through relocation
+	cc := NewConcurrentCuckooFilter(16, 0.01)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				item := fmt.Sprintf("%d-%d", w, i)
+				if err := cc.Insert(item); err != nil && err != ErrFilterFull {
+					t.Error(err)
+					return
+				} else if err == nil && !cc.Delete(item) {
+					t.Errorf("%s could not be deleted", item)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if cc.Count() != 0 {
+		t.Errorf("count is %d, expected 0", cc.Count())
+	}
+	if _, err := cc.MarshalBinary(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCuckooConcurrentLookup(t *testing.T) {
+	c := NewCuckooFilter(100, 0.001)
+	for i := 0; i < 100; i++ {
+		if err := c.Insert(fmt.Sprint(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Lookups don't modify the filter, and hashing is stateless,
+	// so a plain Cuckoo can be read from several goroutines.
+	var wg sync.WaitGroup
+	wg.Add(8)
+	for w := 0; w < 8; w++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if !c.Lookup(fmt.Sprint(i)) {
+					t.Errorf("%d missing", i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -6,29 +6,37 @@
 package cuckoo
 
 import (
-	"bytes"
-	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"math"
 	"math/rand"
-)
 
-// Define the types
-type Bucket []Fingerprint
-type Fingerprint []byte
+	"github.com/taurusgroup/multi-party-sig/filters"
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
 
-var hasher = sha1.New()
+// Fingerprint is an f-bit fingerprint of an item, 0 is reserved for empty slots.
+type Fingerprint uint32
 
 // how many times do we try to move items around during insertion
 const retries = 500
 
-// Set default fingerprint size to 8 bits
-// 8 bit fingerprint size equals to a false positive rate ~= 0.03
-var b_size uint = 8
+// Fingerprints are between 4 and 32 bits long.
+// 4 bits is the smallest size for which semi-sorting makes sense,
+// and 32 bits already gives a false positive rate of ~2e-9 with b = 4.
+const (
+	minFingerprintLength = 4
+	maxFingerprintLength = 32
+)
+
+// maxBucketSize bounds the number of entries per bucket we accept when decoding a filter.
+const maxBucketSize = 8
 
-// Another option is b_size=4, f=16 bit,
-//which correspond to a false positive rate of r ~= 0.0001
+// maxLoadFactor is the highest load we size a filter for. The paper reports ~95%
+// achievable occupancy with b = 4, past that point inserts spend most of their time
+// relocating entries.
+const maxLoadFactor = 0.95
 
 // Set default filter capacity of entries to 4
 // based on the paper https://www.pdl.cmu.edu/PDL-FTP/FS/cuckoo-conext2014.pdf
@@ -36,30 +44,114 @@ var b uint = 4 // number of entries or fingerprints per bucket
 
 // Cuckoo Data structure based on https://www.pdl.cmu.edu/PDL-FTP/FS/cuckoo-conext2014.pdf
 type Cuckoo struct {
-	Buckets []Bucket
-	M       uint // number of buckets
-	B       uint // number of entries per bucket in bits
-	F       uint // fingerprint length in bits
-	N       uint // number of items - filter capacity
+	M uint // number of buckets
+	B uint // number of entries per bucket
+	F uint // fingerprint length in bits
+	N uint // number of items - filter capacity
+	// SemiSorted is true if buckets are stored in semi-sorted form, see table.
+	SemiSorted bool
+
+	// hasher derives bucket indices and fingerprints from items
+	hasher *hasher.Hasher
+	// table holds the packed fingerprints
+	table *table
+	// count is the number of fingerprints currently stored
+	count uint
+	// evictions picks the entry swapped out of a full bucket, see Seed
+	evictions evictions
+}
+
+// evictions is the source of the entries relocate swaps out.
+//
+// It uses math/rand until seeded, and a splitmix64 stream afterwards.
+// It is stored by value, so that copies of a filter don't share their stream.
+type evictions struct {
+	seeded bool
+	state  uint64
+}
+
+// next returns the index of the entry to evict from a bucket of b entries.
+func (e *evictions) next(b uint) int {
+	if !e.seeded {
+		return rand.Intn(int(b))
+	}
+	e.state += 0x9E3779B97F4A7C15
+	z := e.state
+	z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+	z = (z ^ z>>27) * 0x94D049BB133111EB
+	return int((z ^ z>>31) % uint64(b))
+}
+
+// Seed makes later insertions deterministic, by deriving the entries they evict from seed.
+//
+// Two filters with the same parameters and seed, given the same items in the same order,
+// have identical tables. The seed is not part of the encoding, so a decoded filter
+// evicts random entries again unless it is seeded.
+//
+// Use NewCanonicalCuckooFilter, or filters.Build, to build a filter from a set of items
+// which doesn't depend on the order in which they are given.
+func (c *Cuckoo) Seed(seed []byte) {
+	digest := sha256.Sum256(seed)
+	c.evictions = evictions{seeded: true, state: binary.BigEndian.Uint64(digest[:8])}
+}
+
+// Option enables an optional feature when creating a filter.
+type Option func(*options)
+
+type options struct {
+	semiSorted bool
+	hasher     *hasher.Hasher
+}
+
+func newOptions(opts []Option) options {
+	o := options{hasher: hasher.Default}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithSemiSorting stores buckets in semi-sorted form, saving one bit per entry
+// at the cost of slightly slower reads and writes.
+func WithSemiSorting() Option {
+	return func(o *options) {
+		o.semiSorted = true
+	}
+}
+
+// WithHasher replaces the default SHA-1 hash with h.
+//
+// With a keyed hasher, the filter can only be queried by someone who knows the key,
+// which must be passed again to EmptyCuckooFilter when decoding it.
+func WithHasher(h *hasher.Hasher) Option {
+	return func(o *options) {
+		o.hasher = h
+	}
 }
 
-// fingerprintLength follows the formula f >= log2(2b/r) bits
+// fingerprintLength follows the formula f >= log2(2b/e) bits
 // suggested by authors of https://www.pdl.cmu.edu/PDL-FTP/FS/cuckoo-conext2014.pdf
 // e: target false positive rate
 // b: number of entries per bucket
-// Here:
-//	- 8 bit fingerprint size equals to a false positive rate ~= 0.03
-//	- 16 bit fingerprint size equals to a false positive rate ~= 0.0001
-
+// Here, with b = 4:
+//   - e = 0.03 gives a 9 bit fingerprint
+//   - e = 0.0001 gives a 17 bit fingerprint
+//
+// The result is clamped to [minFingerprintLength, maxFingerprintLength].
 func fingerprintLength(b uint, e float64) uint {
+	if e <= 0 {
+		return maxFingerprintLength
+	}
 
-	f := uint(math.Ceil(math.Log(2 * float64(b) / e)))
-	f /= b_size // convert to x bit fingerprint. Default 8 bit fingerprint
+	f := math.Ceil(math.Log2(2 * float64(b) / e))
 
-	if f < 1 {
-		f = 1
+	if f < minFingerprintLength {
+		return minFingerprintLength
+	}
+	if f > maxFingerprintLength {
+		return maxFingerprintLength
 	}
-	return f
+	return uint(f)
 }
 
 func nextPower(i uint) uint {
@@ -91,20 +183,16 @@ func nextPower(i uint) uint {
 	return i
 }
 
-// NewCuckooFilter creates a new cuckoo filter according to the parameters suggested by the authors
-// "because it achieves the best or close-to-best space efficiency for the false positive
-// rates that most practical applications 'A. Broder, M. Mitzenmacher, and A. Broder. Network
-// Applications of Bloom Filters' may be interested in":
-// n: number of items - filter capacity
-// e: false positive rate (e.g., 0.01)
-// returns a pointer to the cuckoo filter
-func NewCuckooFilter(n uint, e float64) *Cuckoo {
-	//b := uint(4) // number of entries or fingerprints per bucket
+// dimensions returns the number of buckets m and the fingerprint length f
+// of a filter holding n items with false positive rate e.
+func dimensions(n uint, e float64) (m, f uint) {
 	// following https://www.pdl.cmu.edu/PDL-FTP/FS/cuckoo-conext2014.pdf optimum recommendations
-	f := fingerprintLength(b, e)
+	f = fingerprintLength(b, e)
+
 	// following https://www.pdl.cmu.edu/PDL-FTP/FS/cuckoo-conext2014.pdf
-	// to calculate the number of buckets
-	m := nextPower(n / f * b_size)
+	// we need enough buckets of b entries to hold n items,
+	// and the table size must be a power of 2 for the partial-key cuckoo hashing to work
+	m = nextPower((n + b - 1) / b)
 
 	// Set a minimum number of buckets
 	// to at least 1 bucket
@@ -112,24 +200,60 @@ func NewCuckooFilter(n uint, e float64) *Cuckoo {
 		m = 1
 	}
 
-	// Make an array of buckets of len m
-	// if m = 4, then buckets = [bucket, bucket, bucket, bucket]
-	buckets := make([]Bucket, m)
-
-	// Initialize each bucket within the array of buckets
-	for i := uint(0); i < m; i++ {
-		buckets[i] = make(Bucket, b) // make a bucket of len b
+	// Double the table if n items would load it beyond what cuckoo hashing can handle
+	if float64(n)/float64(m*b) > maxLoadFactor {
+		m <<= 1
 	}
+	return m, f
+}
+
+// NewCuckooFilter creates a new cuckoo filter according to the parameters suggested by the authors
+// "because it achieves the best or close-to-best space efficiency for the false positive
+// rates that most practical applications 'A. Broder, M. Mitzenmacher, and A. Broder. Network
+// Applications of Bloom Filters' may be interested in":
+// n: number of items - filter capacity
+// e: false positive rate (e.g., 0.01)
+// returns a pointer to the cuckoo filter
+func NewCuckooFilter(n uint, e float64, opts ...Option) *Cuckoo {
+	o := newOptions(opts)
+	m, f := dimensions(n, e)
 
 	// return the created Cuckoo filter with the parameters
 	return &Cuckoo{
-		Buckets: buckets,
-		M:       m,
-		B:       b,
-		F:       f,
-		N:       n,
+		M:          m,
+		B:          b,
+		F:          f,
+		N:          n,
+		SemiSorted: o.semiSorted,
+		hasher:     o.hasher,
+		table:      newTable(m, b, f, o.semiSorted),
 	}
+}
+
+// NewCanonicalCuckooFilter creates a filter sized for items with false positive rate e,
+// and inserts them in canonical order, evicting entries chosen from filters.SetDigest(items).
+//
+// The result only depends on the set of items and the options, so every party building it
+// from the same set obtains the same encoding, whatever the order of items.
+func NewCanonicalCuckooFilter(items []string, e float64, opts ...Option) (*Cuckoo, error) {
+	items = filters.Canonical(items)
+	c := NewCuckooFilter(uint(len(items)), e, opts...)
+	c.Seed(filters.SetDigest(items))
+	for _, item := range items {
+		if err := c.Insert(item); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
 
+// EmptyCuckooFilter creates an empty Cuckoo, ready for unmarshalling.
+//
+// Filters built with a keyed hasher need to be given the same hasher here,
+// since the key is not part of the encoding.
+func EmptyCuckooFilter(opts ...Option) *Cuckoo {
+	o := newOptions(opts)
+	return &Cuckoo{hasher: o.hasher}
 }
 
 // The hashes function would have the inputs:
@@ -137,52 +261,94 @@ func NewCuckooFilter(n uint, e float64) *Cuckoo {
 // other options would be to pass the cuckoo filter struct by value (c Cuckoo) or by reference (c &Cuckoo)
 // but we don't want to copy the struct every time we call the function and it is more efficient to pass
 // a pointer to the struct allowing to modify the struct while the other options would pass a copy of the struct
-// the function hashes returns h1, h2 and the fingerprint
+// the function hashes returns the indices of both candidate buckets and the fingerprint
 func (c *Cuckoo) hashes(data string) (uint, uint, Fingerprint) {
 	// Compute the hash of the data string input
-	h := hash([]byte(data))
-
-	// Get the fingerprint of the hash of the data string
-	// using the f value set in the cuckoo filter struct for the fingerprint length in bits
-	// by slicing the hash from 0 to f
-	f := h[0:c.F]
-
-	// Convert a portion of the first hash value to an unsigned integer using BigEndian
-	i1 := uint(binary.BigEndian.Uint32(h))
-
-	// XOR (the ^ operator) the first hash value with the second hash value
-	// which returns a bit set to 1 for each position
-	//where the corresponding bits of the operands are different.
-	// E.g. 1010 ^ 1100 = 0110
-	// This is used to generate a second hash value different from the first hash value
-	i2 := i1 ^ uint(binary.BigEndian.Uint32(hash(f)))
-
-	// i1 and 12 represent the two possible buckets for the item
-	// while f represents the fingerprint of the item to insert, which is a slice of the hash of the item
-	return i1, i2, Fingerprint(f)
+	h := c.hasher.Sum([]byte(data))
+
+	// Convert the first 4 bytes of the hash to the index of the first bucket
+	i1 := uint(binary.BigEndian.Uint32(h[0:4])) % c.M
+
+	// The fingerprint is taken from the next 4 bytes, so that it's independent of the bucket index
+	f := c.fingerprint(binary.BigEndian.Uint32(h[4:8]))
+
+	// The second bucket is derived from the first one and the fingerprint only,
+	// so that it can also be computed when relocating an entry
+	i2 := c.altIndex(i1, f)
+
+	// i1 and i2 represent the two possible buckets for the item
+	// while f represents the fingerprint of the item to insert
+	return i1, i2, f
 }
 
-func hash(data []byte) []byte {
-	// Compute the fingerprint of the item
-	hasher.Write([]byte(data))
+// fingerprint maps v to a non zero fingerprint of c.F bits, since 0 marks empty slots.
+func (c *Cuckoo) fingerprint(v uint32) Fingerprint {
+	return Fingerprint(uint64(v)%(1<<c.F-1) + 1)
+}
 
-	// Get the SHA1 hash
-	hash := hasher.Sum(nil)
+// altIndex returns the other candidate bucket for fingerprint f stored in bucket i.
+//
+// XOR (the ^ operator) with the hash of the fingerprint returns a bit set to 1 for each position
+// where the corresponding bits of the operands are different. E.g. 1010 ^ 1100 = 0110
+// Applying it twice gives back the original bucket, since M is a power of 2.
+func (c *Cuckoo) altIndex(i uint, f Fingerprint) uint {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(f))
+	return (i ^ uint(binary.BigEndian.Uint32(c.hasher.Sum(buf[:])))) % c.M
+}
 
-	// Reset the hasher for the next use
-	hasher.Reset()
+// insertInto stores f in an empty slot of bucket i, and reports whether there was one.
+func (c *Cuckoo) insertInto(i uint, f Fingerprint) bool {
+	var buf [maxBucketSize]Fingerprint
+	tags := buf[:c.B]
+	c.table.read(i, tags)
+	for j, tag := range tags {
+		if tag == 0 {
+			tags[j] = f
+			c.table.write(i, tags)
+			return true
+		}
+	}
+	return false
+}
 
-	return hash
+// contains reports whether bucket i holds f.
+func (c *Cuckoo) contains(i uint, f Fingerprint) bool {
+	var buf [maxBucketSize]Fingerprint
+	tags := buf[:c.B]
+	c.table.read(i, tags)
+	for _, tag := range tags {
+		if tag == f {
+			return true
+		}
+	}
+	return false
 }
 
-// nextIndex returns the next index for entry, or an error if the bucket is full
-func (b Bucket) nextIndex() (int, error) {
-	for i, f := range b {
-		if f == nil {
-			return i, nil
+// remove clears one occurrence of f from bucket i, and reports whether it was found.
+func (c *Cuckoo) remove(i uint, f Fingerprint) bool {
+	var buf [maxBucketSize]Fingerprint
+	tags := buf[:c.B]
+	c.table.read(i, tags)
+	for j, tag := range tags {
+		if tag == f {
+			tags[j] = 0
+			c.table.write(i, tags)
+			return true
 		}
 	}
-	return -1, errors.New("bucket full")
+	return false
+}
+
+// ErrFilterFull is returned by Insert when no free slot could be found for an item,
+// even after relocating existing entries.
+var ErrFilterFull = errors.New("cuckoo filter full")
+
+// relocation records the content of a bucket before an entry was swapped out of it,
+// so that the swap can be undone if the insertion eventually fails.
+type relocation struct {
+	bucket   uint
+	previous [maxBucketSize]Fingerprint
 }
 
 // Insert adds an item to the cuckoo filter
@@ -206,66 +372,65 @@ func (b Bucket) nextIndex() (int, error) {
 //	    try store in new bucket
 //	    if success -> done
 //
-// The input is a string corresponding to the item to insert in the cuckoo filter
-func (c *Cuckoo) Insert(input string) {
+// The input is a string corresponding to the item to insert in the cuckoo filter.
+// If the filter is too full to accommodate the item, ErrFilterFull is returned
+// and the filter is left exactly as it was before the call.
+func (c *Cuckoo) Insert(input string) error {
 
 	// Get the two possible buckets (i1, i2) for the item and the fingerprint (f) to insert
-	// i1 and i2 only indicate the bucket index in the array of buckets for two possible buckets
 	i1, i2, f := c.hashes(input)
 
-	// first try bucket one to find an empty slot by calling the nextIndex function
-	// pick a bucket from the array of buckets using the modulo operator with l1
-	// b1 is a bucket of type []fingerprint
-	b1 := c.Buckets[i1%c.M]
-
-	// Get i and err from the nextIndex function ("i, err := b1.nextIndex();")
-	// validating that there is an empty slot in the bucket ("err == nil")
-	// by checking if the error is nil
-	if i, err := b1.nextIndex(); err == nil {
-		// if there is an empty slot, insert the fingerprint
-		b1[i] = f
-		// No return value here because we are modifiying the "buckets"
-		// within the Cuckoo struct
-		return
+	// first try bucket one to find an empty slot,
+	// then try bucket two if bucket one is full
+	if c.insertInto(i1, f) || c.insertInto(i2, f) {
+		c.count++
+		return nil
 	}
 
-	// then try bucket two to find an empty slot if bucket one is full
-	b2 := c.Buckets[i2%c.M]
-	if i, err := b2.nextIndex(); err == nil {
-		b2[i] = f
-
-		// No return value here because we are modifiying the "buckets"
-		//within the Cuckoo struct
-		return
+	// else we need to start relocating/shuffling items
+	if !c.relocate(i1, f) {
+		return ErrFilterFull
 	}
+	c.count++
+	return nil
+}
 
-	// else we need to start relocating/shuffling items
-	i := i1
+// relocate makes room for f by moving existing entries to their alternate bucket,
+// starting from bucket i. It reports whether f was stored.
+//
+// If no room could be found after the maximum number of retries, every move is undone,
+// so that the filter is left untouched.
+func (c *Cuckoo) relocate(i uint, f Fingerprint) bool {
+	// Keep track of every swap, so we can put the evicted fingerprints
+	// back where they were if we run out of retries.
+	path := make([]relocation, 0, retries)
 
 	// Using the retries constant, try to relocate/shuffle items around to make space
 	//for a maximum of retries times
 	for r := 0; r < retries; r++ {
-		index := i % c.M
-		entryIndex := rand.Intn(int(c.B))
-		// swap
-		f, c.Buckets[index][entryIndex] = c.Buckets[index][entryIndex], f
-		i = i ^ uint(binary.BigEndian.Uint32(hash(f)))
-		b := c.Buckets[i%c.M]
-		if idx, err := b.nextIndex(); err == nil {
-			b[idx] = f
-			return
+		var buf [maxBucketSize]Fingerprint
+		tags := buf[:c.B]
+		c.table.read(i, tags)
+		path = append(path, relocation{bucket: i, previous: buf})
+
+		// swap with a random entry of the bucket
+		entryIndex := c.evictions.next(c.B)
+		f, tags[entryIndex] = tags[entryIndex], f
+		c.table.write(i, tags)
+
+		// and try to put the evicted entry in its other bucket
+		i = c.altIndex(i, f)
+		if c.insertInto(i, f) {
+			return true
 		}
 	}
-	panic("cuckoo filter full")
-}
 
-func (b Bucket) Contains(f Fingerprint) (int, bool) {
-	for i, x := range b {
-		if bytes.Equal(x, f) {
-			return i, true
-		}
+	// undo the relocations in reverse order
+	for r := len(path) - 1; r >= 0; r-- {
+		step := path[r]
+		c.table.write(step.bucket, step.previous[:c.B])
 	}
-	return -1, false
+	return false
 }
 
 // lookup needle in the cuckoo filter
@@ -274,39 +439,40 @@ func (c *Cuckoo) Lookup(needle string) bool {
 	// Get the two possible buckets (i1, i2) for the item and the fingerprint (f) to lookup
 	i1, i2, f := c.hashes(needle)
 
-	// Check if the fingerprint is in the first bucket
-	_, b1 := c.Buckets[i1%c.M].Contains(f)
-
-	// Check if the fingerprint is in the second bucket
-	_, b2 := c.Buckets[i2%c.M].Contains(f)
-
 	// Return true if the fingerprint is in either bucket
-	return b1 || b2
+	return c.contains(i1, f) || c.contains(i2, f)
 }
 
-// delete the fingerprint from the cuckoo filter
-func (c *Cuckoo) Delete(needle string) {
+// Delete removes one occurrence of needle from the cuckoo filter,
+// and reports whether a matching fingerprint was found.
+func (c *Cuckoo) Delete(needle string) bool {
 
 	// Get the two possible buckets (i1, i2) for the item and the fingerprint (f) to delete
 	i1, i2, f := c.hashes(needle)
 
-	// try to remove from bucket 1
-	b1 := c.Buckets[i1%c.M]
-
-	// if the fingerprint is in the first bucket, set it to nil
-	if ind, ok := b1.Contains(f); ok {
-		b1[ind] = nil
-		return
+	// try to remove from bucket 1, then from bucket 2
+	if c.remove(i1, f) || c.remove(i2, f) {
+		c.count--
+		return true
 	}
 
-	// try to remove from bucket 2
-	b2 := c.Buckets[i2%c.M]
+	return false
+}
+
+// Count returns the number of fingerprints currently stored in the filter.
+func (c *Cuckoo) Count() uint {
+	return c.count
+}
 
-	// if the fingerprint is in the second bucket, set it to nil
-	if ind, ok := b2.Contains(f); ok {
-		b2[ind] = nil
-		return
-	}
+// LoadFactor returns the fraction of occupied slots in the filter.
+func (c *Cuckoo) LoadFactor() float64 {
+	return float64(c.count) / float64(c.M*c.B)
+}
+
+// FalsePositiveRate returns the upper bound 2b/2^f on the false positive rate of the filter,
+// reached when every slot is occupied.
+func (c *Cuckoo) FalsePositiveRate() float64 {
+	return 2 * float64(c.B) / math.Pow(2, float64(c.F))
 }
 
 // func main() {
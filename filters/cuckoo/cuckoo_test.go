@@ -0,0 +1,204 @@
+package cuckoo
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+func TestInsertFull(t *testing.T) {
+	c := NewCuckooFilter(1, 0.01)
+
+	var inserted []string
+	for i := 0; ; i++ {
+		item := fmt.Sprintf("item-%d", i)
+		err := c.Insert(item)
+		if err == ErrFilterFull {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		inserted = append(inserted, item)
+		if uint(len(inserted)) > c.M*c.B {
+			t.Fatal("inserted more items than there are slots")
+		}
+	}
+
+	if c.Count() != uint(len(inserted)) {
+		t.Errorf("count is %d, expected %d", c.Count(), len(inserted))
+	}
+	// a failed insertion must not evict anything
+	for _, item := range inserted {
+		if !c.Lookup(item) {
+			t.Errorf("%s lost after a failed insertion", item)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	c := NewCuckooFilter(10, 0.01)
+	if err := c.Insert("hello"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Delete("hello") {
+		t.Error("expected hello to be deleted")
+	}
+	if c.Lookup("hello") {
+		t.Error("hello still present after delete")
+	}
+	if c.Delete("hello") {
+		t.Error("deleting a missing item should report false")
+	}
+	if c.Count() != 0 {
+		t.Errorf("count is %d, expected 0", c.Count())
+	}
+}
+
+func TestScalable(t *testing.T) {
+	s := NewScalableCuckooFilter(2, 0.001)
+
+	items := make([]string, 500)
+	for i := range items {
+		items[i] = fmt.Sprintf("0x%040d", i)
+		if err := s.Insert(items[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(s.Filters) < 2 {
+		t.Fatalf("expected the filter to grow, found %d sub-filters", len(s.Filters))
+	}
+	if s.Count() != uint(len(items)) {
+		t.Errorf("count is %d, expected %d", s.Count(), len(items))
+	}
+	for _, item := range items {
+		if !s.Lookup(item) {
+			t.Errorf("%s missing", item)
+		}
+	}
+
+	for _, item := range items[:10] {
+		if !s.Delete(item) {
+			t.Errorf("%s could not be deleted", item)
+		}
+	}
+	if s.Count() != uint(len(items)-10) {
+		t.Errorf("count is %d, expected %d", s.Count(), len(items)-10)
+	}
+}
+
+func TestFingerprintLength(t *testing.T) {
+	tests := []struct {
+		e    float64
+		want uint
+	}{
+		{0.03, 9},
+		{0.01, 10},
+		{0.001, 13},
+		{0.0001, 17},
+		{0.5, minFingerprintLength},
+		{1e-12, maxFingerprintLength},
+		{0, maxFingerprintLength},
+	}
+	for _, tt := range tests {
+		if got := fingerprintLength(4, tt.e); got != tt.want {
+			t.Errorf("fingerprintLength(4, %v) = %d, expected %d", tt.e, got, tt.want)
+		}
+	}
+}
+
+func TestSemiSorted(t *testing.T) {
+	c := NewCuckooFilter(100, 0.01, WithSemiSorting())
+	items := make([]string, 100)
+	for i := range items {
+		items[i] = fmt.Sprint(i)
+		if err := c.Insert(items[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, item := range items {
+		if !c.Lookup(item) {
+			t.Errorf("%s missing", item)
+		}
+	}
+	for _, item := range items[:50] {
+		if !c.Delete(item) {
+			t.Errorf("%s could not be deleted", item)
+		}
+	}
+	for _, item := range items[50:] {
+		if !c.Lookup(item) {
+			t.Errorf("%s missing after deleting other items", item)
+		}
+	}
+}
+
+func TestHashers(t *testing.T) {
+	secret := hasher.DeriveKey("cuckoo test", []byte("wallet secret"))
+	for _, id := range []hasher.ID{hasher.SHA1, hasher.SHA256, hasher.BLAKE3, hasher.FNV64, hasher.SipHash} {
+		for _, key := range [][]byte{nil, secret} {
+			h, err := hasher.New(id, key)
+			if err != nil {
+				continue
+			}
+			c := NewCuckooFilter(50, 0.001, WithHasher(h))
+			for i := 0; i < 50; i++ {
+				if err := c.Insert(fmt.Sprint(i)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := 0; i < 50; i++ {
+				if !c.Lookup(fmt.Sprint(i)) {
+					t.Errorf("%s (keyed: %v): %d missing", id, h.Keyed(), i)
+				}
+			}
+		}
+	}
+}
+
+func TestCanonicalCuckooFilter(t *testing.T) {
+	// enough items to load the table at 93%, so that insertions evict entries
+	items := make([]string, 950)
+	for i := range items {
+		items[i] = fmt.Sprintf("item-%d", len(items)-i)
+	}
+	c1, err := NewCanonicalCuckooFilter(items, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+	c2, err := NewCanonicalCuckooFilter(append(items, items[0]), 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1.LoadFactor() < 0.9 {
+		t.Fatalf("load factor is only %f", c1.LoadFactor())
+	}
+
+	data1, _ := c1.MarshalBinary()
+	data2, _ := c2.MarshalBinary()
+	if !bytes.Equal(data1, data2) {
+		t.Error("filters built from the same set differ")
+	}
+
+	// the same seed and insertion order give the same table
+	c3 := NewCuckooFilter(950, 0.01)
+	c4 := NewCuckooFilter(950, 0.01)
+	c3.Seed([]byte("seed"))
+	c4.Seed([]byte("seed"))
+	for _, item := range items {
+		if c3.Insert(item) != nil || c4.Insert(item) != nil {
+			t.Fatal("insertion failed")
+		}
+	}
+	data3, _ := c3.MarshalBinary()
+	data4, _ := c4.MarshalBinary()
+	if !bytes.Equal(data3, data4) {
+		t.Error("filters with the same seed differ")
+	}
+}
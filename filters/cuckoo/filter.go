@@ -0,0 +1,54 @@
+package cuckoo
+
+import (
+	"math"
+
+	"github.com/taurusgroup/multi-party-sig/filters"
+)
+
+func init() {
+	filters.Register("cuckoo", func(n uint, e float64) filters.Filter {
+		return NewCuckooFilter(n, e)
+	})
+	filters.RegisterEstimator("cuckoo", Estimate)
+	filters.RegisterDecoder("cuckoo", func(data []byte) (filters.Filter, error) {
+		c := EmptyCuckooFilter()
+		return c, c.UnmarshalBinary(data)
+	})
+}
+
+var (
+	_ filters.Deleter = (*Cuckoo)(nil)
+	_ filters.Seeder  = (*Cuckoo)(nil)
+)
+
+// Add implements filters.Filter, it is the same as Insert.
+func (c *Cuckoo) Add(item string) error {
+	return c.Insert(item)
+}
+
+// Test implements filters.Filter, it is the same as Lookup.
+func (c *Cuckoo) Test(item string) bool {
+	return c.Lookup(item)
+}
+
+// Len implements filters.Filter, it is the same as Count.
+func (c *Cuckoo) Len() uint {
+	return c.count
+}
+
+// Estimate returns the encoded size and false positive rate of NewCuckooFilter(n, e)
+// holding n items.
+//
+// A lookup compares its fingerprint with the 2b slots of its buckets, of which a fraction
+// n / (m b) is occupied, and each occupied slot matches with probability 1 / (2^f - 1).
+func Estimate(n uint, e float64) (size int, rate float64) {
+	m, f := dimensions(n, e)
+	size = 3 + int(tableLength(m, b, f, false))
+	for _, v := range []uint{m, b, f, n} {
+		size += len(appendUvarint(nil, uint64(v)))
+	}
+	occupied := 2 * float64(n) / float64(m)
+	rate = 1 - math.Pow(1-1/(math.Pow(2, float64(f))-1), occupied)
+	return size, rate
+}
@@ -1,3 +0,0 @@
-module github.com/dlt-science/mpc-wallet-multiple-filters/filters/cuckoo
-
-go 1.0
@@ -0,0 +1,183 @@
+package cuckoo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+// Binary layout produced by MarshalBinary:
+//
+//	version  (1 byte)
+//	hash id  (1 byte, see hasher.ID)
+//	flags    (1 byte, bit 0 set for semi-sorted buckets, bit 1 for a keyed hash)
+//	M, B, F, N (unsigned varints)
+//	packed table, exactly as stored in memory (see table)
+//
+// Since a fingerprint of 0 marks an empty slot, the table is all we need
+// to restore the filter, and it's already as small as the parameters allow.
+const (
+	// encodingVersion is bumped whenever the layout above changes.
+	//
+	// Version 1 stored byte-aligned fingerprints along with an occupancy bitmap,
+	// and used a different fingerprint derivation, so it can't be decoded anymore.
+	encodingVersion byte = 2
+
+	// flagSemiSorted is set in the flags byte when buckets are semi-sorted.
+	flagSemiSorted byte = 1 << 0
+	// flagKeyed is set in the flags byte when the hash is keyed.
+	// The key itself is never encoded.
+	flagKeyed byte = 1 << 1
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+//
+// The result is a compact, versioned encoding of the filter which can be
+// restored with UnmarshalBinary.
+func (c *Cuckoo) MarshalBinary() ([]byte, error) {
+	if c == nil || c.table == nil {
+		return nil, errors.New("cuckoo: nil filter")
+	}
+
+	var flags byte
+	if c.SemiSorted {
+		flags |= flagSemiSorted
+	}
+	if c.hasher.Keyed() {
+		flags |= flagKeyed
+	}
+
+	out := make([]byte, 0, 3+4*binary.MaxVarintLen64+len(c.table.data))
+	out = append(out, encodingVersion, byte(c.hasher.ID()), flags)
+	for _, v := range []uint{c.M, c.B, c.F, c.N} {
+		out = appendUvarint(out, uint64(v))
+	}
+	out = append(out, c.table.data...)
+	return out, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+//
+// It restores a filter produced by MarshalBinary, and returns an error
+// if the data is truncated, has trailing bytes, or describes an invalid filter.
+//
+// A keyed filter can only be decoded into a Cuckoo created by EmptyCuckooFilter
+// with a hasher using the same hash function and key.
+func (c *Cuckoo) UnmarshalBinary(data []byte) error {
+	if len(data) < 3 {
+		return errors.New("cuckoo: data too short")
+	}
+	if data[0] != encodingVersion {
+		return fmt.Errorf("cuckoo: unsupported encoding version %d", data[0])
+	}
+	flags := data[2]
+	if flags&^(flagSemiSorted|flagKeyed) != 0 {
+		return fmt.Errorf("cuckoo: unknown flags %#x", flags)
+	}
+	semiSorted := flags&flagSemiSorted != 0
+
+	h, err := decodeHasher(c.hasher, hasher.ID(data[1]), flags&flagKeyed != 0)
+	if err != nil {
+		return err
+	}
+	data = data[3:]
+
+	var params [4]uint64
+	for i := range params {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("cuckoo: invalid header")
+		}
+		params[i] = v
+		data = data[n:]
+	}
+	m, b, f, n := params[0], params[1], params[2], params[3]
+
+	if m == 0 || m&(m-1) != 0 {
+		return fmt.Errorf("cuckoo: number of buckets %d is not a power of 2", m)
+	}
+	if b == 0 || b > maxBucketSize {
+		return fmt.Errorf("cuckoo: invalid bucket size %d", b)
+	}
+	if f < minFingerprintLength || f > maxFingerprintLength {
+		return fmt.Errorf("cuckoo: invalid fingerprint length %d", f)
+	}
+	if semiSorted && b != semiSortedBucketSize {
+		return fmt.Errorf("cuckoo: semi-sorting requires buckets of size %d", semiSortedBucketSize)
+	}
+
+	// check the table size before allocating anything,
+	// so that a forged header can't make us allocate a huge table.
+	if m > uint64(len(data))*8 {
+		return errors.New("cuckoo: data too short for table")
+	}
+	length := tableLength(uint(m), uint(b), uint(f), semiSorted)
+	if uint64(len(data)) != uint64(length) {
+		return fmt.Errorf("cuckoo: expected %d bytes of table, found %d", length, len(data))
+	}
+
+	t := newTable(uint(m), uint(b), uint(f), semiSorted)
+	copy(t.data, data)
+
+	// the padding bits of the last byte must be unset
+	usedBits := uint(m) * t.bucketBits
+	if usedBits%8 != 0 && t.data[len(t.data)-1]>>(usedBits%8) != 0 {
+		return errors.New("cuckoo: invalid table padding")
+	}
+	if !t.valid(uint(m)) {
+		return errors.New("cuckoo: invalid semi-sorted bucket")
+	}
+
+	c2 := Cuckoo{
+		M:          uint(m),
+		B:          uint(b),
+		F:          uint(f),
+		N:          uint(n),
+		SemiSorted: semiSorted,
+		hasher:     h,
+		table:      t,
+	}
+
+	var buf [maxBucketSize]Fingerprint
+	tags := buf[:b]
+	for i := uint(0); i < c2.M; i++ {
+		t.read(i, tags)
+		for _, tag := range tags {
+			if tag != 0 {
+				c2.count++
+			}
+		}
+	}
+
+	*c = c2
+	return nil
+}
+
+// decodeHasher returns the hasher to use for a decoded filter.
+//
+// Unkeyed hashers are fully described by their id, but keyed ones must be provided
+// beforehand as current, since we don't know the key.
+func decodeHasher(current *hasher.Hasher, id hasher.ID, keyed bool) (*hasher.Hasher, error) {
+	if !keyed {
+		h, err := hasher.New(id, nil)
+		if err != nil {
+			return nil, fmt.Errorf("cuckoo: %w", err)
+		}
+		return h, nil
+	}
+	if current == nil || !current.Keyed() {
+		return nil, errors.New("cuckoo: filter uses a keyed hash, decode it with EmptyCuckooFilter(WithHasher(h))")
+	}
+	if current.ID() != id {
+		return nil, fmt.Errorf("cuckoo: filter uses %s, but hasher is %s", id, current.ID())
+	}
+	return current, nil
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
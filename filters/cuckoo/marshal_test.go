@@ -0,0 +1,163 @@
+package cuckoo
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	for _, semiSorted := range []bool{false, true} {
+		var opts []Option
+		if semiSorted {
+			opts = append(opts, WithSemiSorting())
+		}
+		c := NewCuckooFilter(10, 0.001, opts...)
+		items := make([]string, 10)
+		for i := range items {
+			items[i] = fmt.Sprintf("0x%040d", i)
+			if err := c.Insert(items[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		data, err := c.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c2 := new(Cuckoo)
+		if err := c2.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if c2.M != c.M || c2.B != c.B || c2.F != c.F || c2.N != c.N || c2.SemiSorted != semiSorted {
+			t.Fatalf("parameters differ after round trip: %+v", c2)
+		}
+		if c2.Count() != c.Count() {
+			t.Errorf("count is %d after round trip, expected %d", c2.Count(), c.Count())
+		}
+		for _, item := range items {
+			if !c2.Lookup(item) {
+				t.Errorf("%s missing after round trip", item)
+			}
+		}
+
+		data2, err := c2.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, data2) {
+			t.Error("encoding is not stable across a round trip")
+		}
+	}
+}
+
+func TestMarshalSize(t *testing.T) {
+	c := NewCuckooFilter(10, 0.001)
+	semi := NewCuckooFilter(10, 0.001, WithSemiSorting())
+
+	data, _ := c.MarshalBinary()
+	semiData, _ := semi.MarshalBinary()
+
+	// 4 buckets of 4 entries of 13 bits
+	if got, want := len(data), 7+26; got != want {
+		t.Errorf("encoding is %d bytes, expected %d", got, want)
+	}
+	// 4 buckets of 12 + 4*9 bits
+	if got, want := len(semiData), 7+24; got != want {
+		t.Errorf("semi-sorted encoding is %d bytes, expected %d", got, want)
+	}
+}
+
+func TestMarshalEmpty(t *testing.T) {
+	c := NewCuckooFilter(1, 0.01)
+	data, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2 := new(Cuckoo)
+	if err := c2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if c2.Lookup("hello") {
+		t.Error("empty filter should not contain anything")
+	}
+}
+
+func TestUnmarshalCorrupt(t *testing.T) {
+	c := NewCuckooFilter(4, 0.01)
+	_ = c.Insert("hello")
+	_ = c.Insert("world")
+	data, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withByte := func(i int, b byte) []byte {
+		out := append([]byte{}, data...)
+		out[i] = b
+		return out
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"nil", nil},
+		{"version", withByte(0, encodingVersion+1)},
+		{"hash id", withByte(1, 0xff)},
+		{"flags", withByte(2, 0x80)},
+		{"header only", data[:4]},
+		{"truncated", data[:len(data)-1]},
+		{"trailing", append(append([]byte{}, data...), 0)},
+		{"buckets not power of 2", withByte(3, 3)},
+		{"zero bucket size", withByte(4, 0)},
+		{"large bucket size", withByte(4, maxBucketSize+1)},
+		{"short fingerprint", withByte(5, minFingerprintLength-1)},
+		{"long fingerprint", withByte(5, maxFingerprintLength+1)},
+		{"semi-sorted", withByte(2, flagSemiSorted)},
+		{"huge table", []byte{encodingVersion, byte(hasher.SHA1), 0, 0x80, 0x80, 0x80, 0x80, 0x08, 4, 8, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := new(Cuckoo).UnmarshalBinary(tt.data); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestMarshalKeyed(t *testing.T) {
+	key := hasher.DeriveKey("cuckoo test", []byte("wallet secret"))
+	h, err := hasher.New(hasher.BLAKE3, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCuckooFilter(10, 0.001, WithHasher(h))
+	if err := c.Insert("hello"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without the key, the filter can't be decoded
+	if err := new(Cuckoo).UnmarshalBinary(data); err == nil {
+		t.Error("decoding a keyed filter without its key should fail")
+	}
+	other, _ := hasher.New(hasher.SHA256, key)
+	if err := EmptyCuckooFilter(WithHasher(other)).UnmarshalBinary(data); err == nil {
+		t.Error("decoding a keyed filter with the wrong hash should fail")
+	}
+
+	c2 := EmptyCuckooFilter(WithHasher(h))
+	if err := c2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !c2.Lookup("hello") {
+		t.Error("hello missing after round trip")
+	}
+}
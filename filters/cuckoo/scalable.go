@@ -0,0 +1,85 @@
+package cuckoo
+
+// growthFactor is the ratio between the capacity of consecutive sub-filters.
+const growthFactor = 2
+
+// ScalableCuckoo is a cuckoo filter which grows as items are added.
+//
+// It holds a list of sub-filters: items are always inserted into the newest one,
+// and once it reaches maxLoadFactor or is full, a new sub-filter with growthFactor times the capacity is appended.
+// Lookups and deletions go through every sub-filter.
+//
+// Note that each sub-filter contributes its own false positive rate e, so the overall rate
+// is bounded by len(Filters) * e.
+type ScalableCuckoo struct {
+	Filters []*Cuckoo
+	// E is the target false positive rate used for each sub-filter
+	E float64
+
+	opts []Option
+}
+
+// NewScalableCuckooFilter creates a ScalableCuckoo whose first sub-filter has capacity n
+// and false positive rate e.
+func NewScalableCuckooFilter(n uint, e float64, opts ...Option) *ScalableCuckoo {
+	if n == 0 {
+		n = 1
+	}
+	return &ScalableCuckoo{
+		Filters: []*Cuckoo{NewCuckooFilter(n, e, opts...)},
+		E:       e,
+		opts:    opts,
+	}
+}
+
+// grow appends a new sub-filter with growthFactor times the capacity of the last one.
+func (s *ScalableCuckoo) grow() *Cuckoo {
+	last := s.Filters[len(s.Filters)-1]
+	c := NewCuckooFilter(last.N*growthFactor, s.E, s.opts...)
+	s.Filters = append(s.Filters, c)
+	return c
+}
+
+// Insert adds an item to the newest sub-filter, adding new sub-filters as required.
+//
+// An error is only returned if the item can't be inserted even into a freshly created sub-filter.
+func (s *ScalableCuckoo) Insert(input string) error {
+	c := s.Filters[len(s.Filters)-1]
+	if c.LoadFactor() >= maxLoadFactor {
+		c = s.grow()
+	}
+	if err := c.Insert(input); err != ErrFilterFull {
+		return err
+	}
+	return s.grow().Insert(input)
+}
+
+// Lookup returns true if any of the sub-filters contains needle.
+func (s *ScalableCuckoo) Lookup(needle string) bool {
+	for _, c := range s.Filters {
+		if c.Lookup(needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// Delete removes one occurrence of needle, starting from the newest sub-filter,
+// and reports whether a matching fingerprint was found.
+func (s *ScalableCuckoo) Delete(needle string) bool {
+	for i := len(s.Filters) - 1; i >= 0; i-- {
+		if s.Filters[i].Delete(needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of fingerprints stored across all sub-filters.
+func (s *ScalableCuckoo) Count() uint {
+	var total uint
+	for _, c := range s.Filters {
+		total += c.Count()
+	}
+	return total
+}
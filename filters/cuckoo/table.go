@@ -0,0 +1,177 @@
+package cuckoo
+
+import (
+	"sort"
+	"sync"
+)
+
+// table stores the fingerprints of a cuckoo filter, packed as tightly as possible.
+//
+// Each bucket holds b fingerprints of f bits each, stored contiguously in data without
+// any padding between buckets. A fingerprint of 0 marks an empty slot.
+//
+// In semi-sorted mode (Section 5.2 of https://www.pdl.cmu.edu/PDL-FTP/FS/cuckoo-conext2014.pdf),
+// which requires b = 4, the fingerprints of a bucket are sorted by their 4 low bits.
+// There are only 3876 sorted sequences of four 4-bit values, so this part of the bucket can
+// be stored as a 12-bit index instead of 16 bits, saving one bit per fingerprint.
+// Since the order of fingerprints inside a bucket is not preserved, buckets are
+// always read and written as a whole.
+type table struct {
+	data       []byte
+	b, f       uint
+	semiSorted bool
+	// bucketBits is the number of bits used to store a bucket
+	bucketBits uint
+}
+
+const (
+	// semiSortedBucketSize is the only bucket size supported in semi-sorted mode.
+	semiSortedBucketSize = 4
+	// semiSortedIndexBits is the size of the index replacing the four low nibbles of a bucket.
+	semiSortedIndexBits = 12
+	// semiSortedCombinations is the number of sorted sequences of four 4-bit values.
+	semiSortedCombinations = 3876
+)
+
+var (
+	semiSortedOnce sync.Once
+	// semiSortedTuples maps an index to four sorted nibbles, packed from low to high.
+	semiSortedTuples [semiSortedCombinations]uint16
+	// semiSortedIndex is the inverse of semiSortedTuples.
+	semiSortedIndex map[uint16]uint16
+)
+
+func initSemiSorted() {
+	semiSortedIndex = make(map[uint16]uint16, semiSortedCombinations)
+	i := uint16(0)
+	for a := uint16(0); a < 16; a++ {
+		for b := a; b < 16; b++ {
+			for c := b; c < 16; c++ {
+				for d := c; d < 16; d++ {
+					tuple := a | b<<4 | c<<8 | d<<12
+					semiSortedTuples[i] = tuple
+					semiSortedIndex[tuple] = i
+					i++
+				}
+			}
+		}
+	}
+}
+
+// bucketBits returns the number of bits needed for a bucket of b fingerprints of f bits.
+func bucketBits(b, f uint, semiSorted bool) uint {
+	if semiSorted {
+		return semiSortedIndexBits + b*(f-4)
+	}
+	return b * f
+}
+
+// tableLength returns the number of bytes needed to store m buckets.
+func tableLength(m, b, f uint, semiSorted bool) uint {
+	return (m*bucketBits(b, f, semiSorted) + 7) / 8
+}
+
+func newTable(m, b, f uint, semiSorted bool) *table {
+	if semiSorted {
+		semiSortedOnce.Do(initSemiSorted)
+	}
+	return &table{
+		data:       make([]byte, tableLength(m, b, f, semiSorted)),
+		b:          b,
+		f:          f,
+		semiSorted: semiSorted,
+		bucketBits: bucketBits(b, f, semiSorted),
+	}
+}
+
+// read fills tags with the fingerprints stored in bucket i.
+// tags must have length b.
+func (t *table) read(i uint, tags []Fingerprint) {
+	pos := i * t.bucketBits
+	if !t.semiSorted {
+		for j := range tags {
+			tags[j] = Fingerprint(getBits(t.data, pos, t.f))
+			pos += t.f
+		}
+		return
+	}
+
+	tuple := semiSortedTuples[getBits(t.data, pos, semiSortedIndexBits)]
+	pos += semiSortedIndexBits
+	for j := range tags {
+		high := getBits(t.data, pos, t.f-4)
+		pos += t.f - 4
+		tags[j] = Fingerprint(high<<4 | uint32(tuple>>uint(4*j))&0xf)
+	}
+}
+
+// write stores the fingerprints in tags in bucket i.
+// tags must have length b, and will be reordered in semi-sorted mode.
+func (t *table) write(i uint, tags []Fingerprint) {
+	pos := i * t.bucketBits
+	if !t.semiSorted {
+		for _, tag := range tags {
+			setBits(t.data, pos, t.f, uint32(tag))
+			pos += t.f
+		}
+		return
+	}
+
+	sort.Slice(tags, func(a, b int) bool { return tags[a]&0xf < tags[b]&0xf })
+	var tuple uint16
+	for j, tag := range tags {
+		tuple |= uint16(tag&0xf) << uint(4*j)
+	}
+	setBits(t.data, pos, semiSortedIndexBits, uint32(semiSortedIndex[tuple]))
+	pos += semiSortedIndexBits
+	for _, tag := range tags {
+		setBits(t.data, pos, t.f-4, uint32(tag)>>4)
+		pos += t.f - 4
+	}
+}
+
+// valid checks that every bucket of a semi-sorted table holds a valid index.
+func (t *table) valid(m uint) bool {
+	if !t.semiSorted {
+		return true
+	}
+	for i := uint(0); i < m; i++ {
+		if getBits(t.data, i*t.bucketBits, semiSortedIndexBits) >= semiSortedCombinations {
+			return false
+		}
+	}
+	return true
+}
+
+// getBits returns the n ⩽ 32 bits of data starting at bit pos, least significant bit first.
+func getBits(data []byte, pos, n uint) uint32 {
+	var v uint64
+	for got := uint(0); got < n; {
+		offset := pos % 8
+		take := 8 - offset
+		if take > n-got {
+			take = n - got
+		}
+		chunk := uint64(data[pos/8]>>offset) & (1<<take - 1)
+		v |= chunk << got
+		got += take
+		pos += take
+	}
+	return uint32(v)
+}
+
+// setBits writes the n ⩽ 32 low bits of v to data, starting at bit pos.
+func setBits(data []byte, pos, n uint, v uint32) {
+	for written := uint(0); written < n; {
+		offset := pos % 8
+		take := 8 - offset
+		if take > n-written {
+			take = n - written
+		}
+		mask := byte((1<<take - 1) << offset)
+		chunk := byte(uint64(v)>>written) << offset
+		data[pos/8] = data[pos/8]&^mask | chunk&mask
+		written += take
+		pos += take
+	}
+}
@@ -0,0 +1,49 @@
+package cuckoo
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestTableReadWrite(t *testing.T) {
+	for f := uint(minFingerprintLength); f <= maxFingerprintLength; f++ {
+		for _, semiSorted := range []bool{false, true} {
+			const m = 16
+			tbl := newTable(m, 4, f, semiSorted)
+			want := make([][]Fingerprint, m)
+			for i := range want {
+				want[i] = make([]Fingerprint, 4)
+				for j := range want[i] {
+					want[i][j] = Fingerprint(rand.Uint64() & (1<<f - 1))
+				}
+				tags := append([]Fingerprint{}, want[i]...)
+				tbl.write(uint(i), tags)
+			}
+
+			got := make([]Fingerprint, 4)
+			for i := range want {
+				tbl.read(uint(i), got)
+				if !sameTags(got, want[i]) {
+					t.Errorf("f = %d, semi-sorted = %v: bucket %d is %v, expected %v", f, semiSorted, i, got, want[i])
+				}
+			}
+		}
+	}
+}
+
+// sameTags compares two buckets as multisets.
+func sameTags(a, b []Fingerprint) bool {
+	seen := make(map[Fingerprint]int)
+	for _, tag := range a {
+		seen[tag]++
+	}
+	for _, tag := range b {
+		seen[tag]--
+	}
+	for _, n := range seen {
+		if n != 0 {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
@@ -0,0 +1,413 @@
+// Package embed writes serialized filters into transactions, and reads them back.
+//
+// Each strategy comes with an extractor reading the payload back out of a transaction,
+// and a way to compute the exact cost of the embedding, so that strategies can be compared
+// by the same method. Payloads are opaque bytes, usually the MarshalBinary of a filter
+// or an encoding from the envelope package.
+package embed
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/taurusgroup/multi-party-sig/filters"
+	"github.com/taurusgroup/multi-party-sig/pkg/taproot"
+)
+
+const (
+	// MaxOpReturnPayload is the largest payload of a standard single push OP_RETURN output.
+	MaxOpReturnPayload = filters.OpReturnBudget
+	// MaxDataCarrierSize is the largest standard OP_RETURN script, including the opcodes,
+	// which is the default -datacarriersize of Bitcoin Core.
+	MaxDataCarrierSize = MaxOpReturnPayload + 3
+	// MaxStandardTxWeight is the weight above which transactions aren't relayed.
+	MaxStandardTxWeight = 400_000
+	// WitnessScaleFactor is the weight of a non-witness byte, see BIP-141.
+	WitnessScaleFactor = 4
+)
+
+// ErrNoPayload is returned by extractors when a transaction doesn't carry a payload.
+var ErrNoPayload = errors.New("embed: no payload found")
+
+// Size is the size of one or more Bitcoin transactions, as used to compute fees.
+type Size struct {
+	// Weight is the sum of the weights of the transactions, as defined by BIP-141.
+	Weight int64
+	// VSize is the sum of the virtual sizes of the transactions, i.e. their weights divided by 4,
+	// each rounded up.
+	VSize int64
+}
+
+// Measure returns the total size of txs.
+func Measure(txs ...*wire.MsgTx) Size {
+	var s Size
+	for _, tx := range txs {
+		stripped := int64(tx.SerializeSizeStripped())
+		weight := stripped*(WitnessScaleFactor-1) + int64(tx.SerializeSize())
+		s.Weight += weight
+		s.VSize += (weight + WitnessScaleFactor - 1) / WitnessScaleFactor
+	}
+	return s
+}
+
+// Strategy embeds payloads in Bitcoin transactions.
+type Strategy interface {
+	// Name identifies the strategy, e.g. in reports.
+	Name() string
+	// Template returns the transactions carrying payload, in the order they must be broadcast.
+	//
+	// Each transaction spends a single taproot key path input, signed by a frost.TaprootConfig,
+	// and has a P2TR change output. Amounts, outpoints and keys must be filled in,
+	// and signatures are zero placeholders of the right size, so Measure is exact.
+	Template(payload []byte) ([]*wire.MsgTx, error)
+	// Extract reads the payload back from the last transaction returned by Template.
+	Extract(tx *wire.MsgTx) ([]byte, error)
+}
+
+// OpReturn embeds payloads of at most MaxOpReturnPayload bytes in a single push OP_RETURN output.
+type OpReturn struct{}
+
+// Name implements Strategy.
+func (OpReturn) Name() string {
+	return "op-return"
+}
+
+// Script returns OP_RETURN <payload>.
+func (OpReturn) Script(payload []byte) ([]byte, error) {
+	if len(payload) == 0 {
+		return nil, errors.New("embed: empty payload")
+	}
+	if len(payload) > MaxOpReturnPayload {
+		return nil, fmt.Errorf("embed: payload of %d bytes exceeds the OP_RETURN limit of %d", len(payload), MaxOpReturnPayload)
+	}
+	return txscript.NullDataScript(payload)
+}
+
+// Template implements Strategy.
+func (s OpReturn) Template(payload []byte) ([]*wire.MsgTx, error) {
+	script, err := s.Script(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []*wire.MsgTx{template(wire.NewTxOut(0, script))}, nil
+}
+
+// Extract implements Strategy.
+func (OpReturn) Extract(tx *wire.MsgTx) ([]byte, error) {
+	pushes, err := opReturnPushes(tx)
+	if err != nil {
+		return nil, err
+	}
+	if len(pushes) != 1 {
+		return nil, fmt.Errorf("embed: OP_RETURN output has %d pushes, expected 1", len(pushes))
+	}
+	return pushes[0], nil
+}
+
+// MultiPush embeds payloads in an OP_RETURN output made of several pushes,
+// each at most PushSize bytes.
+//
+// With the default sizes, the output is standard, holds as much as OpReturn, and payloads
+// over 75 bytes take two direct pushes instead of an OP_PUSHDATA1.
+// Larger script sizes need nodes with a larger -datacarriersize to relay the transaction.
+type MultiPush struct {
+	// MaxScriptSize is the largest size of the whole output script, MaxDataCarrierSize if 0.
+	MaxScriptSize int
+	// PushSize is the largest size of a push, DefaultPushSize if 0,
+	// and at most txscript.MaxScriptElementSize.
+	PushSize int
+}
+
+// DefaultPushSize is the largest push with a single opcode, OP_DATA_75.
+const DefaultPushSize = txscript.OP_DATA_75
+
+// Name implements Strategy.
+func (MultiPush) Name() string {
+	return "multi-push"
+}
+
+// Script returns OP_RETURN <chunk 1> ... <chunk n>.
+func (s MultiPush) Script(payload []byte) ([]byte, error) {
+	if len(payload) == 0 {
+		return nil, errors.New("embed: empty payload")
+	}
+	maxSize := s.MaxScriptSize
+	if maxSize == 0 {
+		maxSize = MaxDataCarrierSize
+	}
+	pushSize := s.PushSize
+	if pushSize == 0 {
+		pushSize = DefaultPushSize
+	}
+	if pushSize < 0 || pushSize > txscript.MaxScriptElementSize {
+		return nil, fmt.Errorf("embed: push size %d is not in [1, %d]", pushSize, txscript.MaxScriptElementSize)
+	}
+	b := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN)
+	for _, chunk := range split(payload, pushSize) {
+		b.AddData(chunk)
+	}
+	script, err := b.Script()
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	if len(script) > maxSize {
+		return nil, fmt.Errorf("embed: OP_RETURN script of %d bytes exceeds the limit of %d", len(script), maxSize)
+	}
+	return script, nil
+}
+
+// Template implements Strategy.
+func (s MultiPush) Template(payload []byte) ([]*wire.MsgTx, error) {
+	script, err := s.Script(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []*wire.MsgTx{template(wire.NewTxOut(0, script))}, nil
+}
+
+// Extract implements Strategy.
+func (MultiPush) Extract(tx *wire.MsgTx) ([]byte, error) {
+	pushes, err := opReturnPushes(tx)
+	if err != nil {
+		return nil, err
+	}
+	var payload []byte
+	for _, push := range pushes {
+		payload = append(payload, push...)
+	}
+	return payload, nil
+}
+
+// opReturnPushes returns the pushes of the first OP_RETURN output of tx.
+func opReturnPushes(tx *wire.MsgTx) ([][]byte, error) {
+	for _, out := range tx.TxOut {
+		if len(out.PkScript) == 0 || out.PkScript[0] != txscript.OP_RETURN {
+			continue
+		}
+		pushes, end, err := readPushes(out.PkScript[1:])
+		if err != nil {
+			return nil, err
+		}
+		if len(end) != 0 {
+			return nil, errors.New("embed: OP_RETURN output is not push only")
+		}
+		if len(pushes) == 0 {
+			return nil, ErrNoPayload
+		}
+		return pushes, nil
+	}
+	return nil, ErrNoPayload
+}
+
+// TaprootEnvelope embeds payloads in the script of a taproot leaf, revealed when spending it:
+//
+//	<key> OP_CHECKSIG OP_FALSE OP_IF <chunk 1> ... <chunk n> OP_ENDIF
+//
+// Since the payload is in the witness, it costs a quarter of the weight of output data,
+// and is only limited by MaxStandardTxWeight, but it needs a commit transaction creating
+// the output, and a reveal transaction spending it.
+type TaprootEnvelope struct {
+	// Key signs the reveal transaction, and is the internal key of the output.
+	Key taproot.PublicKey
+}
+
+// Name implements Strategy.
+func (TaprootEnvelope) Name() string {
+	return "taproot-envelope"
+}
+
+// Envelope returns the leaf holding payload.
+func (s TaprootEnvelope) Envelope(payload []byte) (*Envelope, error) {
+	if len(payload) == 0 {
+		return nil, errors.New("embed: empty payload")
+	}
+	key, err := schnorr.ParsePubKey(s.Key)
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	b := txscript.NewScriptBuilder().
+		AddData(s.Key).
+		AddOp(txscript.OP_CHECKSIG).
+		AddOp(txscript.OP_FALSE).
+		AddOp(txscript.OP_IF)
+	for _, chunk := range split(payload, txscript.MaxScriptElementSize) {
+		b.AddData(chunk)
+	}
+	script, err := b.AddOp(txscript.OP_ENDIF).Script()
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+
+	leaf := txscript.NewBaseTapLeaf(script)
+	tree := txscript.AssembleTaprootScriptTree(leaf)
+	controlBlock := tree.LeafMerkleProofs[0].ToControlBlock(key)
+	controlBlockBytes, err := controlBlock.ToBytes()
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	rootHash := tree.RootNode.TapHash()
+	pkScript, err := txscript.PayToTaprootScript(txscript.ComputeTaprootOutputKey(key, rootHash[:]))
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	return &Envelope{
+		Leaf:         leaf,
+		PkScript:     pkScript,
+		ControlBlock: controlBlockBytes,
+	}, nil
+}
+
+// Template implements Strategy.
+//
+// The first transaction commits to the envelope in its first output,
+// which the second transaction spends to reveal it.
+func (s TaprootEnvelope) Template(payload []byte) ([]*wire.MsgTx, error) {
+	e, err := s.Envelope(payload)
+	if err != nil {
+		return nil, err
+	}
+	commit := template(wire.NewTxOut(0, e.PkScript))
+	commitHash := commit.TxHash()
+	reveal := wire.NewMsgTx(wire.TxVersion)
+	reveal.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&commitHash, 0), nil, e.Witness(make(taproot.Signature, taproot.SignatureLen))))
+	reveal.AddTxOut(wire.NewTxOut(0, placeholderPkScript))
+	if w := Measure(reveal).Weight; w > MaxStandardTxWeight {
+		return nil, fmt.Errorf("embed: reveal transaction weight %d exceeds the standard limit of %d", w, MaxStandardTxWeight)
+	}
+	return []*wire.MsgTx{commit, reveal}, nil
+}
+
+// Extract implements Strategy, and returns the payload of the first input revealing an envelope.
+func (TaprootEnvelope) Extract(tx *wire.MsgTx) ([]byte, error) {
+	for _, in := range tx.TxIn {
+		script := tapscript(in.Witness)
+		if script == nil {
+			continue
+		}
+		if payload, err := readEnvelope(script); err == nil {
+			return payload, nil
+		}
+	}
+	return nil, ErrNoPayload
+}
+
+// Envelope is a taproot leaf holding a payload.
+type Envelope struct {
+	Leaf txscript.TapLeaf
+	// PkScript is the script of the output committing to the leaf.
+	PkScript []byte
+	// ControlBlock proves that the leaf is committed to by PkScript.
+	ControlBlock []byte
+}
+
+// SigHash returns the BIP-342 hash to sign with SIGHASH_DEFAULT, for input i of tx spending the envelope.
+func (e *Envelope) SigHash(tx *wire.MsgTx, i int, fetcher txscript.PrevOutputFetcher) ([]byte, error) {
+	sigHashes := txscript.NewTxSigHashes(tx, fetcher)
+	hash, err := txscript.CalcTapscriptSignaturehash(sigHashes, txscript.SigHashDefault, tx, i, fetcher, e.Leaf)
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	return hash, nil
+}
+
+// Witness returns the witness of an input spending the envelope with sig.
+func (e *Envelope) Witness(sig taproot.Signature) wire.TxWitness {
+	return wire.TxWitness{sig, e.Leaf.Script, e.ControlBlock}
+}
+
+// tapscript returns the script of a script path spend, or nil.
+func tapscript(witness wire.TxWitness) []byte {
+	// an annex is the last element if there are at least two, and starts with 0x50
+	if len(witness) >= 2 && len(witness[len(witness)-1]) > 0 && witness[len(witness)-1][0] == txscript.TaprootAnnexTag {
+		witness = witness[:len(witness)-1]
+	}
+	if len(witness) < 2 {
+		return nil
+	}
+	if _, err := txscript.ParseControlBlock(witness[len(witness)-1]); err != nil {
+		return nil
+	}
+	return witness[len(witness)-2]
+}
+
+// readEnvelope returns the concatenated pushes between OP_FALSE OP_IF and OP_ENDIF in script.
+func readEnvelope(script []byte) ([]byte, error) {
+	t := txscript.MakeScriptTokenizer(0, script)
+	prev := byte(txscript.OP_INVALIDOPCODE)
+	for t.Next() {
+		if prev != txscript.OP_FALSE || t.Opcode() != txscript.OP_IF {
+			prev = t.Opcode()
+			continue
+		}
+		pushes, rest, err := readPushes(script[t.ByteIndex():])
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) == 0 || rest[0] != txscript.OP_ENDIF || len(pushes) == 0 {
+			return nil, ErrNoPayload
+		}
+		var payload []byte
+		for _, push := range pushes {
+			payload = append(payload, push...)
+		}
+		return payload, nil
+	}
+	if err := t.Err(); err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	return nil, ErrNoPayload
+}
+
+// readPushes reads data pushes from the start of script,
+// and returns them along with the rest of the script.
+func readPushes(script []byte) ([][]byte, []byte, error) {
+	var pushes [][]byte
+	t := txscript.MakeScriptTokenizer(0, script)
+	offset := 0
+	for t.Next() {
+		op := t.Opcode()
+		switch {
+		case op == txscript.OP_0:
+			// AddData pushes a single zero byte as OP_0, and payloads are never empty
+			pushes = append(pushes, []byte{0})
+		case op >= txscript.OP_1 && op <= txscript.OP_16:
+			pushes = append(pushes, []byte{op - txscript.OP_1 + 1})
+		case op == txscript.OP_1NEGATE:
+			pushes = append(pushes, []byte{0x81})
+		case op <= txscript.OP_PUSHDATA4:
+			pushes = append(pushes, t.Data())
+		default:
+			return pushes, script[offset:], nil
+		}
+		offset = int(t.ByteIndex())
+	}
+	if err := t.Err(); err != nil {
+		return nil, nil, fmt.Errorf("embed: %w", err)
+	}
+	return pushes, nil, nil
+}
+
+// split cuts data into chunks of at most size bytes.
+func split(data []byte, size int) [][]byte {
+	chunks := make([][]byte, 0, (len(data)+size-1)/size)
+	for len(data) > size {
+		chunks = append(chunks, data[:size])
+		data = data[size:]
+	}
+	return append(chunks, data)
+}
+
+// placeholderPkScript is a P2TR script, with the size of any other.
+var placeholderPkScript = append([]byte{txscript.OP_1, txscript.OP_DATA_32}, make([]byte, 32)...)
+
+// template returns a transaction spending a taproot key path input, with out and a change output.
+func template(out *wire.TxOut) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, wire.TxWitness{make([]byte, taproot.SignatureLen)}))
+	tx.AddTxOut(out)
+	tx.AddTxOut(wire.NewTxOut(0, placeholderPkScript))
+	return tx
+}
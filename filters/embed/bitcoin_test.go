@@ -0,0 +1,183 @@
+package embed_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/taurusgroup/multi-party-sig/filters/embed"
+	"github.com/taurusgroup/multi-party-sig/pkg/taproot"
+)
+
+func payload(n int) []byte {
+	data := make([]byte, n)
+	_, _ = rand.Read(data)
+	// a small last chunk is pushed with a single opcode, which extractors must handle
+	if n > 0 {
+		data[n-1] = 5
+	}
+	return data
+}
+
+func roundTrip(t *testing.T, s embed.Strategy, data []byte) embed.Size {
+	t.Helper()
+	txs, err := s.Template(data)
+	if err != nil {
+		t.Fatalf("%s: %v", s.Name(), err)
+	}
+	extracted, err := s.Extract(txs[len(txs)-1])
+	if err != nil {
+		t.Fatalf("%s: %v", s.Name(), err)
+	}
+	if !bytes.Equal(extracted, data) {
+		t.Errorf("%s: extracted payload differs", s.Name())
+	}
+	return embed.Measure(txs...)
+}
+
+func TestMeasure(t *testing.T) {
+	txs, err := embed.OpReturn{}.Template(payload(embed.MaxOpReturnPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 186 bytes without the witness, and 68 bytes of marker, flag and witness
+	size := embed.Measure(txs...)
+	if size.Weight != 186*3+254 || size.VSize != 203 {
+		t.Errorf("unexpected size %+v", size)
+	}
+	if double := embed.Measure(txs[0], txs[0]); double.Weight != 2*size.Weight || double.VSize != 2*size.VSize {
+		t.Errorf("sizes should add up, got %+v", double)
+	}
+}
+
+func TestOpReturn(t *testing.T) {
+	for _, n := range []int{1, 40, embed.MaxOpReturnPayload} {
+		roundTrip(t, embed.OpReturn{}, payload(n))
+		script, err := embed.OpReturn{}.Script(payload(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if txscript.GetScriptClass(script) != txscript.NullDataTy || len(script) > embed.MaxDataCarrierSize {
+			t.Errorf("OP_RETURN script of %d bytes should be standard", n)
+		}
+	}
+	if _, err := (embed.OpReturn{}).Template(payload(embed.MaxOpReturnPayload + 1)); err == nil {
+		t.Error("payload over the limit should be rejected")
+	}
+
+	txs, err := embed.MultiPush{MaxScriptSize: 10_000}.Template(payload(600))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = (embed.OpReturn{}).Extract(txs[0]); err == nil {
+		t.Error("multiple pushes should be rejected")
+	}
+	if _, err = (embed.OpReturn{}).Extract(wire.NewMsgTx(wire.TxVersion)); !errors.Is(err, embed.ErrNoPayload) {
+		t.Errorf("expected ErrNoPayload, got %v", err)
+	}
+}
+
+func TestMultiPush(t *testing.T) {
+	roundTrip(t, embed.MultiPush{}, payload(embed.MaxOpReturnPayload))
+	if _, err := (embed.MultiPush{}).Template(payload(embed.MaxOpReturnPayload + 1)); err == nil {
+		t.Error("payload over the standard limit should be rejected")
+	}
+	script, err := (embed.MultiPush{}).Script(payload(embed.MaxOpReturnPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pushes, _ := txscript.PushedData(script); len(pushes) != 2 {
+		t.Errorf("expected 2 pushes with the default push size, got %d", len(pushes))
+	}
+
+	s := embed.MultiPush{MaxScriptSize: 10_000}
+	for _, n := range []int{embed.DefaultPushSize, embed.DefaultPushSize + 1, txscript.MaxScriptElementSize + 1, 3000} {
+		roundTrip(t, s, payload(n))
+	}
+	large := embed.MultiPush{MaxScriptSize: 10_000, PushSize: txscript.MaxScriptElementSize}
+	for _, n := range []int{txscript.MaxScriptElementSize, txscript.MaxScriptElementSize + 1, 3000} {
+		roundTrip(t, large, payload(n))
+	}
+	if _, err = (embed.MultiPush{PushSize: txscript.MaxScriptElementSize + 1}).Script(payload(10)); err == nil {
+		t.Error("push size over the element size limit should be rejected")
+	}
+	script, err = s.Script(payload(3000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !txscript.IsPushOnlyScript(script[1:]) {
+		t.Error("multi-push script should be push only")
+	}
+	if _, err = s.Template(payload(10_000)); err == nil {
+		t.Error("payload over the limit should be rejected")
+	}
+}
+
+func TestTaprootEnvelope(t *testing.T) {
+	sk, pk, err := taproot.GenKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := embed.TaprootEnvelope{Key: pk}
+	data := payload(3000)
+	size := roundTrip(t, s, data)
+
+	// a real reveal transaction, spending a commit output
+	e, err := s.Envelope(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := wire.NewTxOut(10_000, e.PkScript)
+	outPoint := wire.OutPoint{Index: 1}
+	fetcher := txscript.NewCannedPrevOutputFetcher(commit.PkScript, commit.Value)
+	reveal := wire.NewMsgTx(wire.TxVersion)
+	reveal.AddTxIn(wire.NewTxIn(&outPoint, nil, nil))
+	reveal.AddTxOut(wire.NewTxOut(9_000, append([]byte{txscript.OP_1, txscript.OP_DATA_32}, pk...)))
+	hash, err := e.SigHash(reveal, 0, fetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := sk.Sign(rand.Reader, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reveal.TxIn[0].Witness = e.Witness(sig)
+
+	vm, err := txscript.NewEngine(commit.PkScript, reveal, 0, txscript.StandardVerifyFlags, nil,
+		txscript.NewTxSigHashes(reveal, fetcher), commit.Value, fetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = vm.Execute(); err != nil {
+		t.Fatalf("reveal transaction should be valid: %v", err)
+	}
+	extracted, err := s.Extract(reveal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(extracted, data) {
+		t.Error("extracted payload differs")
+	}
+
+	txs, err := s.Template(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if embed.Measure(txs[1]) != embed.Measure(reveal) {
+		t.Error("template size should be exact")
+	}
+	multiPush := roundTrip(t, embed.MultiPush{MaxScriptSize: 10_000}, data)
+	if size.VSize >= multiPush.VSize {
+		t.Errorf("envelope of %d vbytes should be cheaper than OP_RETURN of %d vbytes", size.VSize, multiPush.VSize)
+	}
+
+	if _, err = s.Extract(txs[0]); !errors.Is(err, embed.ErrNoPayload) {
+		t.Errorf("expected ErrNoPayload, got %v", err)
+	}
+	if _, err = s.Template(payload(embed.MaxStandardTxWeight)); err == nil {
+		t.Error("reveal over the standard weight should be rejected")
+	}
+}
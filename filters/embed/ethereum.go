@@ -0,0 +1,284 @@
+package embed
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"golang.org/x/crypto/sha3"
+)
+
+// Cost is the gas paid for an Ethereum embedding.
+type Cost struct {
+	// Gas is the gas of the transaction: its intrinsic gas, plus the gas of the execution
+	// for strategies which run code.
+	Gas uint64
+	// BlobGas is the blob gas of the transaction, which is priced separately, see EIP-4844.
+	BlobGas uint64
+}
+
+// Fee returns the fee paid at the given gas price and blob gas price, in wei.
+func (c Cost) Fee(gasPrice, blobGasPrice *big.Int) *big.Int {
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(c.Gas), gasPrice)
+	if c.BlobGas == 0 {
+		return fee
+	}
+	return fee.Add(fee, new(big.Int).Mul(new(big.Int).SetUint64(c.BlobGas), blobGasPrice))
+}
+
+// EthereumStrategy embeds payloads in Ethereum transactions.
+type EthereumStrategy interface {
+	// Name identifies the strategy, e.g. in reports.
+	Name() string
+	// Cost returns the gas paid to embed payload in a transaction.
+	Cost(payload []byte) (Cost, error)
+}
+
+// IntrinsicGas returns the gas charged for a transaction calling a contract or an account
+// with data and accessList before any execution, since the Shanghai upgrade.
+func IntrinsicGas(data []byte, accessList types.AccessList) uint64 {
+	gas := params.TxGas
+	for _, b := range data {
+		if b == 0 {
+			gas += params.TxDataZeroGas
+		} else {
+			gas += params.TxDataNonZeroGasEIP2028
+		}
+	}
+	gas += uint64(len(accessList)) * params.TxAccessListAddressGas
+	gas += uint64(accessList.StorageKeys()) * params.TxAccessListStorageKeyGas
+	return gas
+}
+
+// DefaultMethod is the method called by Calldata when none is set.
+const DefaultMethod = "store(bytes)"
+
+// Calldata embeds payloads as the ABI encoded bytes argument of a contract call.
+//
+// Its cost doesn't include the execution of the contract, which may ignore the call.
+type Calldata struct {
+	// Method is the signature of the method called, with a single bytes argument, DefaultMethod if empty.
+	Method string
+}
+
+// Name implements EthereumStrategy.
+func (Calldata) Name() string {
+	return "calldata"
+}
+
+// Selector returns the first 4 bytes of the hash of the method signature.
+func (s Calldata) Selector() []byte {
+	method := s.Method
+	if method == "" {
+		method = DefaultMethod
+	}
+	return keccak256([]byte(method))[:4]
+}
+
+// Data returns the calldata of a call with payload as argument:
+// the selector, then the offset of the argument, its length and payload padded to 32 bytes.
+func (s Calldata) Data(payload []byte) []byte {
+	padded := (len(payload) + 31) / 32 * 32
+	data := make([]byte, 4+32+32+padded)
+	copy(data, s.Selector())
+	data[4+31] = 32
+	binary.BigEndian.PutUint64(data[4+32+24:], uint64(len(payload)))
+	copy(data[4+64:], payload)
+	return data
+}
+
+// Cost implements EthereumStrategy.
+func (s Calldata) Cost(payload []byte) (Cost, error) {
+	return Cost{Gas: IntrinsicGas(s.Data(payload), nil)}, nil
+}
+
+// Extract reads the payload back from calldata returned by Data.
+func (s Calldata) Extract(data []byte) ([]byte, error) {
+	if len(data) < 4+64 || string(data[:4]) != string(s.Selector()) {
+		return nil, ErrNoPayload
+	}
+	args := data[4:]
+	offset, err := abiUint(args[:32], len(args))
+	if err != nil || offset+32 > len(args) {
+		return nil, errors.New("embed: invalid offset in calldata")
+	}
+	length, err := abiUint(args[offset:offset+32], len(args))
+	if err != nil || offset+32+length > len(args) {
+		return nil, errors.New("embed: invalid length in calldata")
+	}
+	return args[offset+32 : offset+32+length], nil
+}
+
+// abiUint reads a 32 byte big endian integer, which must be less than limit.
+func abiUint(word []byte, limit int) (int, error) {
+	v := new(big.Int).SetBytes(word)
+	if !v.IsInt64() || v.Int64() >= int64(limit) {
+		return 0, errors.New("embed: integer out of range")
+	}
+	return int(v.Int64()), nil
+}
+
+// LogTopic is the only topic of the logs emitted by LogCode, so they can be queried with eth_getLogs.
+// It is the hash of "Filter", and not of an event signature, since the log data isn't ABI encoded.
+var LogTopic = common.BytesToHash(keccak256([]byte("Filter")))
+
+// LogCode is the runtime code of a contract emitting its calldata as the data of a log:
+//
+//	CALLDATASIZE PUSH0 PUSH0 CALLDATACOPY
+//	PUSH32 LogTopic CALLDATASIZE PUSH0 LOG1 STOP
+var LogCode = append(append(
+	[]byte{0x36, 0x5f, 0x5f, 0x37, 0x7f}, LogTopic[:]...),
+	0x36, 0x5f, 0xa1, 0x00)
+
+// LogInitCode deploys LogCode:
+//
+//	PUSH1 len(LogCode) DUP1 PUSH1 9 PUSH0 CODECOPY PUSH0 RETURN
+var LogInitCode = append([]byte{0x60, byte(len(LogCode)), 0x80, 0x60, 0x09, 0x5f, 0x39, 0x5f, 0xf3}, LogCode...)
+
+// Gas of the cheapest opcodes, as in the yellow paper.
+const (
+	gasQuickStep   = 2
+	gasFastestStep = 3
+)
+
+// Log embeds payloads as the data of a log, by calling a contract deployed with LogInitCode.
+// The transaction data is the payload itself.
+type Log struct{}
+
+// Name implements EthereumStrategy.
+func (Log) Name() string {
+	return "log"
+}
+
+// Cost implements EthereumStrategy, and includes the exact execution cost of LogCode.
+func (Log) Cost(payload []byte) (Cost, error) {
+	n := uint64(len(payload))
+	words := (n + 31) / 32
+	memory := words*params.MemoryGas + words*words/params.QuadCoeffDiv
+	execution := 3*gasQuickStep + // CALLDATASIZE PUSH0 PUSH0
+		gasFastestStep + words*params.CopyGas + memory + // CALLDATACOPY
+		gasFastestStep + 2*gasQuickStep + // PUSH32 CALLDATASIZE PUSH0
+		params.LogGas + params.LogTopicGas + n*params.LogDataGas // LOG1, with memory already expanded
+	return Cost{Gas: IntrinsicGas(payload, nil) + execution}, nil
+}
+
+// Extract reads the payload back from a log emitted by LogCode.
+func (Log) Extract(log *types.Log) ([]byte, error) {
+	if log == nil || len(log.Topics) != 1 || log.Topics[0] != LogTopic {
+		return nil, ErrNoPayload
+	}
+	return log.Data, nil
+}
+
+const (
+	// blobElementPayload is the number of payload bytes in a field element of a blob,
+	// whose first byte is zero so that it is less than the BLS12-381 modulus.
+	blobElementPayload = params.BlobTxBytesPerFieldElement - 1
+	// BlobCapacity is the number of payload bytes in a blob.
+	BlobCapacity = params.BlobTxFieldElementsPerBlob * blobElementPayload
+	// MaxBlobs is the number of blobs which fit in a block, and so in a transaction.
+	MaxBlobs = params.MaxBlobGasPerBlock / params.BlobTxBlobGasPerBlob
+	// blobLengthSize is the size of the length prefixing the payload.
+	blobLengthSize = 4
+)
+
+// BlobSidecar embeds payloads in the blobs of an EIP-4844 transaction.
+//
+// The payload is prefixed with its length on 4 bytes, and written 31 bytes per field element.
+// The transaction itself only carries the versioned hashes of the KZG commitments to the blobs,
+// and the blobs are pruned by nodes after about 18 days.
+type BlobSidecar struct{}
+
+// Name implements EthereumStrategy.
+func (BlobSidecar) Name() string {
+	return "blob"
+}
+
+// Blobs returns the number of blobs needed for a payload of n bytes.
+func (BlobSidecar) Blobs(n int) int {
+	return (blobLengthSize + n + BlobCapacity - 1) / BlobCapacity
+}
+
+// Cost implements EthereumStrategy, for a transaction without data.
+func (s BlobSidecar) Cost(payload []byte) (Cost, error) {
+	blobs := s.Blobs(len(payload))
+	if blobs > MaxBlobs {
+		return Cost{}, fmt.Errorf("embed: payload of %d bytes needs %d blobs, more than %d", len(payload), blobs, MaxBlobs)
+	}
+	return Cost{Gas: IntrinsicGas(nil, nil), BlobGas: uint64(blobs) * params.BlobTxBlobGasPerBlob}, nil
+}
+
+// Sidecar returns the blobs holding payload, with their KZG commitments and proofs.
+// The BlobHashes of the transaction carrying them must be set to sidecar.BlobHashes().
+func (s BlobSidecar) Sidecar(payload []byte) (*types.BlobTxSidecar, error) {
+	if _, err := s.Cost(payload); err != nil {
+		return nil, err
+	}
+	data := binary.BigEndian.AppendUint32(make([]byte, 0, blobLengthSize+len(payload)), uint32(len(payload)))
+	data = append(data, payload...)
+
+	sidecar := &types.BlobTxSidecar{}
+	for _, chunk := range split(data, BlobCapacity) {
+		var blob kzg4844.Blob
+		for i := 0; len(chunk) > 0; i++ {
+			n := copy(blob[i*params.BlobTxBytesPerFieldElement+1:(i+1)*params.BlobTxBytesPerFieldElement], chunk)
+			chunk = chunk[n:]
+		}
+		commitment, err := kzg4844.BlobToCommitment(blob)
+		if err != nil {
+			return nil, fmt.Errorf("embed: %w", err)
+		}
+		proof, err := kzg4844.ComputeBlobProof(blob, commitment)
+		if err != nil {
+			return nil, fmt.Errorf("embed: %w", err)
+		}
+		sidecar.Blobs = append(sidecar.Blobs, blob)
+		sidecar.Commitments = append(sidecar.Commitments, commitment)
+		sidecar.Proofs = append(sidecar.Proofs, proof)
+	}
+	return sidecar, nil
+}
+
+// Extract reads the payload back from a sidecar, after checking its KZG proofs,
+// and that its commitments match the versioned hashes of the transaction.
+func (BlobSidecar) Extract(sidecar *types.BlobTxSidecar, blobHashes []common.Hash) ([]byte, error) {
+	if sidecar == nil || len(sidecar.Blobs) == 0 {
+		return nil, ErrNoPayload
+	}
+	if len(sidecar.Commitments) != len(sidecar.Blobs) || len(sidecar.Proofs) != len(sidecar.Blobs) {
+		return nil, errors.New("embed: sidecar has mismatched lengths")
+	}
+	hashes := sidecar.BlobHashes()
+	if len(hashes) != len(blobHashes) {
+		return nil, fmt.Errorf("embed: sidecar has %d blobs, the transaction %d", len(hashes), len(blobHashes))
+	}
+	data := make([]byte, 0, len(sidecar.Blobs)*BlobCapacity)
+	for i := range sidecar.Blobs {
+		if hashes[i] != blobHashes[i] {
+			return nil, fmt.Errorf("embed: blob %d doesn't match the transaction", i)
+		}
+		if err := kzg4844.VerifyBlobProof(sidecar.Blobs[i], sidecar.Commitments[i], sidecar.Proofs[i]); err != nil {
+			return nil, fmt.Errorf("embed: blob %d: %w", i, err)
+		}
+		for j := 0; j < params.BlobTxFieldElementsPerBlob; j++ {
+			element := sidecar.Blobs[i][j*params.BlobTxBytesPerFieldElement : (j+1)*params.BlobTxBytesPerFieldElement]
+			data = append(data, element[1:]...)
+		}
+	}
+	length := binary.BigEndian.Uint32(data)
+	if uint64(length) > uint64(len(data)-blobLengthSize) {
+		return nil, errors.New("embed: invalid payload length in blobs")
+	}
+	return data[blobLengthSize : blobLengthSize+int(length)], nil
+}
+
+func keccak256(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	_, _ = h.Write(data)
+	return h.Sum(nil)
+}
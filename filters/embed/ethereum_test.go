@@ -0,0 +1,173 @@
+package embed_test
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+	"github.com/taurusgroup/multi-party-sig/filters/embed"
+)
+
+func TestIntrinsicGas(t *testing.T) {
+	data := payload(1000)
+	data[10], data[20] = 0, 0
+	accessList := types.AccessList{{Address: common.Address{1}, StorageKeys: []common.Hash{{1}, {2}}}}
+	for _, al := range []types.AccessList{nil, accessList} {
+		expected, err := core.IntrinsicGas(data, al, false, true, true, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gas := embed.IntrinsicGas(data, al); gas != expected {
+			t.Errorf("intrinsic gas is %d, expected %d", gas, expected)
+		}
+	}
+}
+
+func TestCalldata(t *testing.T) {
+	bytesType, err := abi.NewType("bytes", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := abi.Arguments{{Type: bytesType}}
+
+	s := embed.Calldata{}
+	for _, n := range []int{0, 1, 32, 1000} {
+		data := payload(n)
+		calldata := s.Data(data)
+		packed, err := args.Pack(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(calldata[4:], packed) {
+			t.Errorf("calldata of %d bytes isn't ABI encoded", n)
+		}
+		extracted, err := s.Extract(calldata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(extracted, data) {
+			t.Error("extracted payload differs")
+		}
+		cost, err := s.Cost(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cost.Gas != embed.IntrinsicGas(calldata, nil) || cost.BlobGas != 0 {
+			t.Errorf("unexpected cost %+v", cost)
+		}
+	}
+
+	if _, err = (embed.Calldata{Method: "other(bytes)"}).Extract(s.Data(payload(10))); !errors.Is(err, embed.ErrNoPayload) {
+		t.Errorf("expected ErrNoPayload, got %v", err)
+	}
+	truncated := s.Data(payload(100))[:100]
+	if _, err = s.Extract(truncated); err == nil {
+		t.Error("truncated calldata should be rejected")
+	}
+}
+
+func TestLog(t *testing.T) {
+	cfg := &runtime.Config{
+		ChainConfig: params.AllDevChainProtocolChanges,
+		Random:      &common.Hash{},
+		GasLimit:    10_000_000,
+	}
+	code, address, _, err := runtime.Create(embed.LogInitCode, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, embed.LogCode) {
+		t.Fatal("init code should deploy LogCode")
+	}
+
+	s := embed.Log{}
+	for _, n := range []int{0, 1, 100, 5000} {
+		data := payload(n)
+		before := len(cfg.State.Logs())
+		_, left, err := runtime.Call(address, data, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cost, err := s.Cost(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used := cfg.GasLimit - left; used != cost.Gas-embed.IntrinsicGas(data, nil) {
+			t.Errorf("execution with %d bytes used %d gas, expected %d", n, used, cost.Gas-embed.IntrinsicGas(data, nil))
+		}
+
+		logs := cfg.State.Logs()
+		if len(logs) != before+1 {
+			t.Fatal("expected a log")
+		}
+		extracted, err := s.Extract(logs[len(logs)-1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(extracted, data) {
+			t.Error("extracted payload differs")
+		}
+	}
+
+	if _, err = s.Extract(&types.Log{Topics: []common.Hash{{1}}}); !errors.Is(err, embed.ErrNoPayload) {
+		t.Errorf("expected ErrNoPayload, got %v", err)
+	}
+}
+
+func TestBlobSidecar(t *testing.T) {
+	s := embed.BlobSidecar{}
+	if s.Blobs(embed.BlobCapacity-4) != 1 || s.Blobs(embed.BlobCapacity-3) != 2 {
+		t.Error("the payload and its length should fill a blob")
+	}
+
+	data := payload(embed.BlobCapacity + 1000)
+	sidecar, err := s.Sidecar(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := types.NewTx(&types.BlobTx{
+		ChainID:    uint256.NewInt(1),
+		GasTipCap:  uint256.NewInt(1),
+		GasFeeCap:  uint256.NewInt(1),
+		Gas:        params.TxGas,
+		BlobFeeCap: uint256.NewInt(1),
+		BlobHashes: sidecar.BlobHashes(),
+		Sidecar:    sidecar,
+	})
+
+	cost, err := s.Cost(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost.BlobGas != tx.BlobGas() || cost.Gas != tx.Gas() {
+		t.Errorf("cost %+v doesn't match the transaction", cost)
+	}
+	if fee := cost.Fee(big.NewInt(10), big.NewInt(3)); fee.Uint64() != 10*params.TxGas+3*2*params.BlobTxBlobGasPerBlob {
+		t.Errorf("unexpected fee %v", fee)
+	}
+
+	extracted, err := s.Extract(tx.BlobTxSidecar(), tx.BlobHashes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(extracted, data) {
+		t.Error("extracted payload differs")
+	}
+
+	hashes := tx.BlobHashes()
+	hashes[0], hashes[1] = hashes[1], hashes[0]
+	if _, err = s.Extract(sidecar, hashes); err == nil {
+		t.Error("blobs should match the transaction")
+	}
+	if _, err = s.Sidecar(payload(embed.MaxBlobs * embed.BlobCapacity)); err == nil {
+		t.Error("payload over the blob limit should be rejected")
+	}
+}
@@ -0,0 +1,164 @@
+// Package envelope frames a message and a serialized filter, so that both can be
+// embedded in a single payload and recovered in one pass.
+//
+// Binary layout produced by Encode:
+//
+//	magic    (4 bytes, "MPCF")
+//	version  (1 byte)
+//	type     (1 byte, see Type)
+//	message length (unsigned varint)
+//	message
+//	filter length  (unsigned varint)
+//	filter   (as returned by MarshalBinary)
+//
+// The overhead is 8 bytes for messages and filters shorter than 128 bytes, see Size.
+package envelope
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/taurusgroup/multi-party-sig/filters"
+)
+
+// Version is the current version of the encoding, bumped whenever the layout changes.
+const Version byte = 1
+
+// magic starts every envelope.
+var magic = [4]byte{'M', 'P', 'C', 'F'}
+
+// Type identifies the filter of an envelope on a single byte, instead of its registered name.
+type Type byte
+
+// Filter types, which must never be renumbered.
+const (
+	Bloom         Type = 1
+	CountingBloom Type = 2
+	Cuckoo        Type = 3
+	Xor           Type = 4
+	GCS           Type = 5
+)
+
+var names = map[Type]string{
+	Bloom:         "bloom",
+	CountingBloom: "counting-bloom",
+	Cuckoo:        "cuckoo",
+	Xor:           "xor",
+	GCS:           "gcs",
+}
+
+// String returns the name t is registered as in the filters package.
+func (t Type) String() string {
+	if name, ok := names[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Type(%d)", byte(t))
+}
+
+// TypeOf returns the Type of the filter registered as name.
+func TypeOf(name string) (Type, error) {
+	for t, n := range names {
+		if n == name {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("envelope: no type for filter %q", name)
+}
+
+// Envelope is a message along with a serialized filter.
+type Envelope struct {
+	Type    Type
+	Message []byte
+	// Filter is the encoding of the filter, as returned by its MarshalBinary method.
+	Filter []byte
+}
+
+// ErrInvalid is returned by Decode when the data isn't a valid envelope.
+var ErrInvalid = errors.New("envelope: invalid data")
+
+// Size returns the length of the encoding of a message and filter of the given lengths.
+func Size(messageLen, filterLen int) int {
+	return len(magic) + 2 +
+		len(binary.AppendUvarint(nil, uint64(messageLen))) + messageLen +
+		len(binary.AppendUvarint(nil, uint64(filterLen))) + filterLen
+}
+
+// Encode returns the encoding of e.
+func Encode(e Envelope) ([]byte, error) {
+	if _, ok := names[e.Type]; !ok {
+		return nil, fmt.Errorf("envelope: unknown filter type %d", byte(e.Type))
+	}
+	if len(e.Filter) == 0 {
+		return nil, errors.New("envelope: empty filter")
+	}
+	out := make([]byte, 0, Size(len(e.Message), len(e.Filter)))
+	out = append(out, magic[:]...)
+	out = append(out, Version, byte(e.Type))
+	out = binary.AppendUvarint(out, uint64(len(e.Message)))
+	out = append(out, e.Message...)
+	out = binary.AppendUvarint(out, uint64(len(e.Filter)))
+	return append(out, e.Filter...), nil
+}
+
+// Wrap encodes message along with f, registered as name.
+func Wrap(message []byte, name string, f filters.Filter) ([]byte, error) {
+	t, err := TypeOf(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := f.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("envelope: %w", err)
+	}
+	return Encode(Envelope{Type: t, Message: message, Filter: data})
+}
+
+// Decode parses an envelope produced by Encode.
+//
+// It returns an error wrapping ErrInvalid if data is truncated, has trailing bytes,
+// or doesn't start with the magic bytes, and fails for unsupported versions and types.
+// The message and filter of the result are sub-slices of data.
+func Decode(data []byte) (*Envelope, error) {
+	if len(data) < len(magic)+2 || !bytes.Equal(data[:len(magic)], magic[:]) {
+		return nil, fmt.Errorf("%w: missing magic bytes", ErrInvalid)
+	}
+	data = data[len(magic):]
+	if data[0] != Version {
+		return nil, fmt.Errorf("envelope: unsupported version %d", data[0])
+	}
+	e := &Envelope{Type: Type(data[1])}
+	if _, ok := names[e.Type]; !ok {
+		return nil, fmt.Errorf("envelope: unknown filter type %d", data[1])
+	}
+	data = data[2:]
+
+	var err error
+	if e.Message, data, err = readBytes(data); err != nil {
+		return nil, fmt.Errorf("%w: message: %v", ErrInvalid, err)
+	}
+	if e.Filter, data, err = readBytes(data); err != nil {
+		return nil, fmt.Errorf("%w: filter: %v", ErrInvalid, err)
+	}
+	if len(e.Filter) == 0 {
+		return nil, fmt.Errorf("%w: empty filter", ErrInvalid)
+	}
+	if len(data) != 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrInvalid, len(data))
+	}
+	return e, nil
+}
+
+// readBytes reads a length prefixed byte string from data, and returns it with the rest of data.
+func readBytes(data []byte) ([]byte, []byte, error) {
+	length, n := binary.Uvarint(data)
+	if n <= 0 || n != len(binary.AppendUvarint(nil, length)) {
+		return nil, nil, errors.New("invalid length")
+	}
+	data = data[n:]
+	if length > uint64(len(data)) {
+		return nil, nil, fmt.Errorf("%d bytes announced, %d left", length, len(data))
+	}
+	return data[:length:length], data[length:], nil
+}
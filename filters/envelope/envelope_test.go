@@ -0,0 +1,106 @@
+package envelope_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/taurusgroup/multi-party-sig/filters"
+	"github.com/taurusgroup/multi-party-sig/filters/bloom"
+	_ "github.com/taurusgroup/multi-party-sig/filters/cuckoo"
+	"github.com/taurusgroup/multi-party-sig/filters/envelope"
+	_ "github.com/taurusgroup/multi-party-sig/filters/gcs"
+	_ "github.com/taurusgroup/multi-party-sig/filters/xor"
+)
+
+func TestTypes(t *testing.T) {
+	for _, name := range filters.Names() {
+		typ, err := envelope.TypeOf(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ.String() != name {
+			t.Errorf("type of %s is %s", name, typ)
+		}
+	}
+	if _, err := envelope.TypeOf("unknown"); err == nil {
+		t.Error("unknown filter has a type")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	message := []byte("hello1")
+	for _, name := range filters.Names() {
+		f, err := filters.Build(name, []string{"a", "b", "c"}, 0.01)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := envelope.Wrap(message, name, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filterData, _ := f.MarshalBinary()
+		if len(data) != envelope.Size(len(message), len(filterData)) {
+			t.Errorf("%s: size is %d, expected %d", name, len(data), envelope.Size(len(message), len(filterData)))
+		}
+
+		e, err := envelope.Decode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Type.String() != name || !bytes.Equal(e.Message, message) || !bytes.Equal(e.Filter, filterData) {
+			t.Errorf("%s: decoded %v", name, e)
+		}
+	}
+
+	// the filter can be restored from the envelope
+	data, err := envelope.Wrap(nil, "bloom", bloom.NewBloomFilter(10, 0.01))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := envelope.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Message) != 0 || e.Type != envelope.Bloom {
+		t.Errorf("decoded %v", e)
+	}
+	if err := bloom.EmptyBloomFilter().UnmarshalBinary(e.Filter); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	valid, err := envelope.Encode(envelope.Envelope{Type: envelope.Cuckoo, Message: []byte("message"), Filter: []byte{1, 2, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := envelope.Decode(valid); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < len(valid); i++ {
+		if _, err := envelope.Decode(valid[:i]); err == nil {
+			t.Errorf("decoded %d truncated bytes", i)
+		}
+	}
+	if _, err := envelope.Decode(append(valid, 0)); !errors.Is(err, envelope.ErrInvalid) {
+		t.Errorf("expected ErrInvalid for trailing bytes, got %v", err)
+	}
+
+	for name, data := range map[string][]byte{
+		"magic":        append([]byte("MPCX"), valid[4:]...),
+		"version":      append([]byte("MPCF\x02"), valid[5:]...),
+		"type":         append([]byte("MPCF\x01\x00"), valid[6:]...),
+		"long length":  []byte("MPCF\x01\x03\x80\x00\x01\x00"),
+		"empty filter": []byte("MPCF\x01\x03\x00\x00"),
+	} {
+		if _, err := envelope.Decode(data); err == nil {
+			t.Errorf("%s: decoded invalid data", name)
+		}
+	}
+
+	if _, err := envelope.Encode(envelope.Envelope{Type: 42, Filter: []byte{1}}); err == nil {
+		t.Error("encoded an unknown type")
+	}
+}
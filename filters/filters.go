@@ -0,0 +1,154 @@
+// Package filters defines the interface shared by the membership filters of this module,
+// and a registry to create them by name.
+//
+// Filter implementations register themselves when their package is imported,
+// so a program selecting filters by name only needs a blank import:
+//
+//	import _ "github.com/taurusgroup/multi-party-sig/filters/cuckoo"
+//
+//	f, err := filters.New("cuckoo", 10, 0.001)
+//
+// Package filters/all imports every built-in filter.
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Filter is an approximate set membership filter.
+//
+// Test never returns false for an item which was added, but may return true
+// for an item which wasn't, with a probability set when creating the filter.
+type Filter interface {
+	// Add inserts an item into the filter.
+	// It fails if the filter can't hold any more items.
+	Add(item string) error
+	// Test returns true if item may be in the filter, and false if it definitely isn't.
+	Test(item string) bool
+	// Len returns the number of items in the filter.
+	Len() uint
+	// MarshalBinary returns a deterministic encoding of the filter.
+	MarshalBinary() ([]byte, error)
+}
+
+// Deleter is implemented by filters supporting the removal of items.
+type Deleter interface {
+	Filter
+	// Delete removes an item, and reports whether it was found.
+	// Deleting an item which was never added may remove another item sharing its fingerprint.
+	Delete(item string) bool
+}
+
+// Constructor creates an empty filter with capacity n and false positive rate e.
+type Constructor func(n uint, e float64) Filter
+
+// Estimator predicts the encoded size in bytes and the false positive rate of the filter
+// created with capacity n and false positive rate e, once it holds n items.
+//
+// It is used by Plan to compare filters without building them.
+type Estimator func(n uint, e float64) (size int, rate float64)
+
+// Decoder restores a filter from the output of its MarshalBinary method.
+type Decoder func(data []byte) (Filter, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Constructor{}
+	estimators = map[string]Estimator{}
+	decoders   = map[string]Decoder{}
+)
+
+// ErrUnknownFilter is returned by New for names which weren't registered.
+var ErrUnknownFilter = errors.New("filters: unknown filter")
+
+// Register makes a filter available by name.
+//
+// It is meant to be called from the init function of the package implementing the filter,
+// and panics if name is empty or already registered.
+func Register(name string, constructor Constructor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if name == "" || constructor == nil {
+		panic("filters: Register called with an empty name or nil constructor")
+	}
+	if _, ok := registry[name]; ok {
+		panic("filters: Register called twice for " + name)
+	}
+	registry[name] = constructor
+}
+
+// RegisterEstimator sets the Estimator of the filter registered as name.
+//
+// Like Register, it is meant to be called from an init function, and panics if name
+// isn't registered yet, or already has an estimator.
+func RegisterEstimator(name string, estimator Estimator) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; !ok || estimator == nil {
+		panic("filters: RegisterEstimator called with an unknown name or nil estimator")
+	}
+	if _, ok := estimators[name]; ok {
+		panic("filters: RegisterEstimator called twice for " + name)
+	}
+	estimators[name] = estimator
+}
+
+// RegisterDecoder sets the Decoder of the filter registered as name.
+//
+// Like Register, it is meant to be called from an init function, and panics if name
+// isn't registered yet, or already has a decoder.
+func RegisterDecoder(name string, decoder Decoder) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; !ok || decoder == nil {
+		panic("filters: RegisterDecoder called with an unknown name or nil decoder")
+	}
+	if _, ok := decoders[name]; ok {
+		panic("filters: RegisterDecoder called twice for " + name)
+	}
+	decoders[name] = decoder
+}
+
+// Decode restores the filter registered as name from data, as returned by its MarshalBinary method.
+//
+// Filters are decoded with the hasher the registry creates them with,
+// so filters using a keyed hasher must be decoded by their own package.
+func Decode(name string, data []byte) (Filter, error) {
+	registryMu.RLock()
+	decoder, ok := decoders[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownFilter, name)
+	}
+	f, err := decoder(data)
+	if err != nil {
+		return nil, fmt.Errorf("filters: decode %s: %w", name, err)
+	}
+	return f, nil
+}
+
+// New creates the filter registered as name, with capacity n and false positive rate e.
+func New(name string, n uint, e float64) (Filter, error) {
+	registryMu.RLock()
+	constructor, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownFilter, name)
+	}
+	return constructor(n, e), nil
+}
+
+// Names returns the sorted names of all registered filters.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,90 @@
+package filters_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/taurusgroup/multi-party-sig/filters"
+	_ "github.com/taurusgroup/multi-party-sig/filters/all"
+)
+
+func TestRegistry(t *testing.T) {
+	names := filters.Names()
+	if fmt.Sprint(names) != "[bloom counting-bloom cuckoo gcs xor]" {
+		t.Fatalf("registered filters are %v", names)
+	}
+
+	if _, err := filters.New("unknown", 10, 0.01); !errors.Is(err, filters.ErrUnknownFilter) {
+		t.Errorf("expected ErrUnknownFilter, got %v", err)
+	}
+
+	for _, name := range names {
+		f, err := filters.New(name, 10, 0.001)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			if err := f.Add(fmt.Sprint(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if f.Len() != 10 {
+			t.Errorf("%s: length is %d, expected 10", name, f.Len())
+		}
+		for i := 0; i < 10; i++ {
+			if !f.Test(fmt.Sprint(i)) {
+				t.Errorf("%s: %d missing", name, i)
+			}
+		}
+		if _, err := f.MarshalBinary(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+
+		if d, ok := f.(filters.Deleter); ok {
+			if !d.Delete("0") || d.Len() != 9 {
+				t.Errorf("%s: failed to delete an item", name)
+			}
+		}
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice should panic")
+		}
+	}()
+	filters.Register("bloom", func(uint, float64) filters.Filter { return nil })
+}
+
+func TestDecode(t *testing.T) {
+	for _, name := range filters.Names() {
+		f, err := filters.Build(name, []string{"a", "b", "c"}, 0.01)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := f.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := filters.Decode(name, data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		again, _ := decoded.MarshalBinary()
+		if string(again) != string(data) {
+			t.Errorf("%s: decoded filter has a different encoding", name)
+		}
+		if decoded.Len() != 3 || !decoded.Test("a") || !decoded.Test("c") {
+			t.Errorf("%s: decoded filter lost items", name)
+		}
+
+		if _, err := filters.Decode(name, data[:len(data)-1]); err == nil {
+			t.Errorf("%s: decoded truncated data", name)
+		}
+	}
+	if _, err := filters.Decode("unknown", nil); !errors.Is(err, filters.ErrUnknownFilter) {
+		t.Errorf("expected ErrUnknownFilter, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package gcs
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/taurusgroup/multi-party-sig/filters"
+)
+
+func init() {
+	filters.Register("gcs", func(n uint, e float64) filters.Filter {
+		p, m := Parameters(e)
+		return NewBuilder(n, p, m, [KeySize]byte{})
+	})
+	filters.RegisterEstimator("gcs", Estimate)
+	filters.RegisterDecoder("gcs", decode)
+}
+
+var _ filters.Filter = (*Builder)(nil)
+
+// Builder collects items, and builds a GCS from them when it is queried or serialized.
+//
+// The filter created by the registry uses an all-zero key, which is fine to measure sizes,
+// but real filters should use a key both sides agree on, like BIP-158's block hash.
+type Builder struct {
+	items  []string
+	seen   map[string]struct{}
+	p      uint8
+	m      uint64
+	key    [KeySize]byte
+	filter *GCS
+	// decoded is set for builders of a decoded filter, whose items are unknown
+	decoded bool
+}
+
+// NewBuilder returns a Builder expecting about n items, which builds filters with p, m and key.
+func NewBuilder(n uint, p uint8, m uint64, key [KeySize]byte) *Builder {
+	return &Builder{
+		items: make([]string, 0, n),
+		seen:  make(map[string]struct{}, n),
+		p:     p,
+		m:     m,
+		key:   key,
+	}
+}
+
+// ErrDecoded is returned when adding items to a decoded filter.
+var ErrDecoded = errors.New("gcs: can't add items to a decoded filter")
+
+// Add implements filters.Filter.
+//
+// The filter is rebuilt on the next call to Test or MarshalBinary.
+// Items can't be added to a filter restored by filters.Decode, since the others are unknown.
+func (b *Builder) Add(item string) error {
+	if b.decoded {
+		return ErrDecoded
+	}
+	if _, ok := b.seen[item]; ok {
+		return nil
+	}
+	b.seen[item] = struct{}{}
+	b.items = append(b.items, item)
+	b.filter = nil
+	return nil
+}
+
+// Build returns the filter of all the items added so far.
+func (b *Builder) Build() (*GCS, error) {
+	if b.filter == nil {
+		g, err := NewGCSFilter(b.items, b.p, b.m, b.key)
+		if err != nil {
+			return nil, err
+		}
+		b.filter = g
+	}
+	return b.filter, nil
+}
+
+// Test implements filters.Filter.
+//
+// It returns false if the filter can't be built, which only happens with invalid parameters.
+func (b *Builder) Test(item string) bool {
+	g, err := b.Build()
+	return err == nil && g.Test(item)
+}
+
+// Len implements filters.Filter, it returns the number of distinct items added.
+func (b *Builder) Len() uint {
+	if b.decoded {
+		return uint(b.filter.N)
+	}
+	return uint(len(b.items))
+}
+
+// MarshalBinary implements filters.Filter, using the encoding of GCS.
+func (b *Builder) MarshalBinary() ([]byte, error) {
+	g, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return g.MarshalBinary()
+}
+
+// Estimate returns the expected encoded size and false positive rate of a filter
+// of n distinct items, with the parameters p and m given by Parameters(e).
+//
+// Unlike other filters, the size depends on the items: deltas between sorted hashes are
+// close to exponentially distributed with mean m, so each one takes p + 1 bits, plus
+// 1 / (e^(2^p / m) - 1) bits on average for the unary coded quotient.
+func Estimate(n uint, e float64) (size int, rate float64) {
+	p, m := Parameters(e)
+	quotient := 1 / math.Expm1(math.Ldexp(1, int(p))/float64(m))
+	bits := float64(n) * (float64(p) + 1 + quotient)
+	size = 2 + len(binary.AppendUvarint(nil, m)) + len(appendCompactSize(nil, uint64(n))) + int(math.Ceil(bits/8))
+	return size, 1 / float64(m)
+}
+
+// decode implements filters.Decoder, returning a Builder of the decoded filter.
+// Like the registry's filters, it uses an all-zero key.
+func decode(data []byte) (filters.Filter, error) {
+	g := EmptyGCSFilter([KeySize]byte{})
+	if err := g.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return &Builder{p: g.P, m: g.M, filter: g, decoded: true}, nil
+}
@@ -0,0 +1,237 @@
+// Based on:
+// https://github.com/bitcoin/bips/blob/master/bip-0158.mediawiki
+// https://github.com/btcsuite/btcd/tree/master/btcutil/gcs
+
+package gcs
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+// Parameters of the BIP-158 basic block filter.
+const (
+	// BasicP is the Golomb-Rice coding parameter of basic filters.
+	BasicP = 19
+	// BasicM is the inverse false positive rate of basic filters.
+	BasicM = 784931
+)
+
+// maxP bounds the Golomb-Rice parameter, so that remainders fit in a uint64.
+const maxP = 32
+
+// KeySize is the size of the SipHash key of a filter.
+const KeySize = 16
+
+// GCS is a Golomb-coded set, as used by BIP-158 compact block filters.
+//
+// Each item is hashed with SipHash-2-4 into [0, N*M), and the sorted hashes are
+// delta-encoded with a Golomb-Rice code of parameter P. The false positive rate is 1/M,
+// and the filter takes about P + 1.5 bits per item when M ≈ 1.497 * 2^P.
+//
+// Like the binary fuse filter, it is built once from the full set of items.
+type GCS struct {
+	P uint8  // Golomb-Rice parameter, remainders are P bits long
+	M uint64 // inverse false positive rate
+	N uint32 // number of items
+
+	// key is the SipHash key, which BIP-158 sets to the first 16 bytes of the block hash
+	key [KeySize]byte
+	// data is the Golomb-Rice coded stream of sorted hash deltas
+	data []byte
+}
+
+// Parameters returns the Golomb-Rice parameter p and inverse false positive rate m
+// of a filter with false positive rate e.
+//
+// Like BIP-158, m is chosen around 1.497 * 2^p, which minimizes the size of the filter.
+func Parameters(e float64) (p uint8, m uint64) {
+	if e <= 0 || e >= 1 {
+		return BasicP, BasicM
+	}
+	m = uint64(math.Ceil(1 / e))
+	rounded := math.Round(math.Log2(float64(m) / 1.497137))
+	switch {
+	case rounded < 1:
+		p = 1
+	case rounded > maxP:
+		p = maxP
+	default:
+		p = uint8(rounded)
+	}
+	return p, m
+}
+
+// EmptyGCSFilter returns a filter with no parameters, to be filled by UnmarshalBinary.
+//
+// Since the key is not encoded, it must be provided to query decoded filters.
+func EmptyGCSFilter(key [KeySize]byte) *GCS {
+	return &GCS{key: key}
+}
+
+// NewGCSFilter builds a filter of items with parameters p and m, and SipHash key.
+//
+// Items are deduplicated, so the same set of items always gives the same filter.
+func NewGCSFilter(items []string, p uint8, m uint64, key [KeySize]byte) (*GCS, error) {
+	if p == 0 || p > maxP {
+		return nil, errors.New("gcs: p must be between 1 and 32")
+	}
+	if m == 0 {
+		return nil, errors.New("gcs: m must be positive")
+	}
+
+	unique := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		unique[item] = struct{}{}
+	}
+	if uint64(len(unique)) > math.MaxUint32 {
+		return nil, errors.New("gcs: too many items")
+	}
+
+	g := &GCS{P: p, M: m, N: uint32(len(unique)), key: key}
+	if overflows(g.N, m) {
+		return nil, errors.New("gcs: n * m overflows")
+	}
+
+	values := make([]uint64, 0, len(unique))
+	for item := range unique {
+		values = append(values, g.hash(item))
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	var w bitWriter
+	var last uint64
+	for _, v := range values {
+		delta := v - last
+		last = v
+		for q := delta >> p; q > 0; q-- {
+			w.writeBit(true)
+		}
+		w.writeBit(false)
+		w.writeBits(delta, uint(p))
+	}
+	g.data = w.bytes
+	return g, nil
+}
+
+func overflows(n uint32, m uint64) bool {
+	hi, _ := bits.Mul64(uint64(n), m)
+	return hi != 0
+}
+
+// hash maps an item to [0, N*M), as described in BIP-158.
+func (g *GCS) hash(item string) uint64 {
+	k0 := binary.LittleEndian.Uint64(g.key[0:8])
+	k1 := binary.LittleEndian.Uint64(g.key[8:16])
+	h := hasher.SipHash24(k0, k1, []byte(item))
+	hi, _ := bits.Mul64(h, uint64(g.N)*g.M)
+	return hi
+}
+
+// Test returns true if needle may be in the filter,
+// and false if it definitely isn't.
+func (g *GCS) Test(needle string) bool {
+	if g.N == 0 {
+		return false
+	}
+	target := g.hash(needle)
+
+	r := bitReader{data: g.data}
+	var value uint64
+	for i := uint32(0); i < g.N; i++ {
+		delta, ok := r.readDelta(uint(g.P))
+		if !ok {
+			return false
+		}
+		value += delta
+		switch {
+		case value == target:
+			return true
+		case value > target:
+			return false
+		}
+	}
+	return false
+}
+
+// Len returns the number of distinct items in the filter.
+func (g *GCS) Len() uint {
+	return uint(g.N)
+}
+
+// FalsePositiveRate returns the false positive rate 1/M of the filter.
+func (g *GCS) FalsePositiveRate() float64 {
+	return 1 / float64(g.M)
+}
+
+// bitWriter appends bits to a byte slice, most significant bit first, as BIP-158 does.
+type bitWriter struct {
+	bytes []byte
+	// used is the number of bits used in the last byte, 8 if it's full
+	used uint
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if len(w.bytes) == 0 || w.used == 8 {
+		w.bytes = append(w.bytes, 0)
+		w.used = 0
+	}
+	if bit {
+		w.bytes[len(w.bytes)-1] |= 0x80 >> w.used
+	}
+	w.used++
+}
+
+// writeBits writes the n low bits of v, most significant first.
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	for i := n; i > 0; i-- {
+		w.writeBit(v>>(i-1)&1 == 1)
+	}
+}
+
+// bitReader reads bits written by bitWriter.
+type bitReader struct {
+	data []byte
+	pos  uint
+}
+
+func (r *bitReader) readBit() (bit, ok bool) {
+	if r.pos >= uint(len(r.data))*8 {
+		return false, false
+	}
+	bit = r.data[r.pos/8]&(0x80>>(r.pos%8)) != 0
+	r.pos++
+	return bit, true
+}
+
+// readDelta reads a Golomb-Rice coded value with parameter p.
+func (r *bitReader) readDelta(p uint) (uint64, bool) {
+	var q uint64
+	for {
+		bit, ok := r.readBit()
+		if !ok {
+			return 0, false
+		}
+		if !bit {
+			break
+		}
+		q++
+	}
+	var rem uint64
+	for i := uint(0); i < p; i++ {
+		bit, ok := r.readBit()
+		if !ok {
+			return 0, false
+		}
+		rem <<= 1
+		if bit {
+			rem |= 1
+		}
+	}
+	return q<<p | rem, true
+}
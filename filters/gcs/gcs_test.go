@@ -0,0 +1,123 @@
+package gcs
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/gcs"
+)
+
+var testKey = [KeySize]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+func items(from, to int) []string {
+	out := make([]string, 0, to-from)
+	for i := from; i < to; i++ {
+		out = append(out, fmt.Sprintf("0x%040d", i))
+	}
+	return out
+}
+
+func TestGCS(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 1000} {
+		g, err := NewGCSFilter(items(0, n), BasicP, BasicM, testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Len() != uint(n) {
+			t.Errorf("length is %d, expected %d", g.Len(), n)
+		}
+		for _, item := range items(0, n) {
+			if !g.Test(item) {
+				t.Fatalf("n = %d: %s missing", n, item)
+			}
+		}
+		for _, item := range items(n, n+1000) {
+			if g.Test(item) {
+				t.Errorf("n = %d: unexpected false positive %s", n, item)
+			}
+		}
+	}
+}
+
+func TestFalsePositiveRate(t *testing.T) {
+	p, m := Parameters(0.01)
+	g, err := NewGCSFilter(items(0, 1000), p, m, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	falsePositives := 0
+	for _, item := range items(1000, 101000) {
+		if g.Test(item) {
+			falsePositives++
+		}
+	}
+	// expect ~1000
+	if falsePositives > 1300 {
+		t.Errorf("%d false positives out of 100000", falsePositives)
+	}
+}
+
+func TestParameters(t *testing.T) {
+	if p, m := Parameters(1.0 / BasicM); p != BasicP || m != BasicM {
+		t.Errorf("Parameters(1/%d) = (%d, %d), expected the basic filter parameters", BasicM, p, m)
+	}
+	if p, _ := Parameters(0.5); p != 1 {
+		t.Errorf("p is %d, expected 1", p)
+	}
+}
+
+// TestBIP158 checks that filters match the btcd implementation of BIP-158.
+func TestBIP158(t *testing.T) {
+	for _, n := range []int{0, 1, 300} {
+		data := make([][]byte, n)
+		for i, item := range items(0, n) {
+			data[i] = []byte(item)
+		}
+		expected, err := gcs.BuildGCSFilter(BasicP, BasicM, testKey, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedBytes, err := expected.NBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g, err := NewGCSFilter(items(0, n), BasicP, BasicM, testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(g.BIP158(), expectedBytes) {
+			t.Errorf("n = %d: filter differs from btcd", n)
+		}
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	forward := items(0, 100)
+	backward := make([]string, 0, 110)
+	for i := len(forward) - 1; i >= 0; i-- {
+		backward = append(backward, forward[i])
+	}
+	backward = append(backward, forward[:10]...)
+
+	a, _ := NewGCSFilter(forward, 10, 1000, testKey)
+	b, _ := NewGCSFilter(backward, 10, 1000, testKey)
+	dataA, _ := a.MarshalBinary()
+	dataB, _ := b.MarshalBinary()
+	if !bytes.Equal(dataA, dataB) {
+		t.Error("the order of items changed the filter")
+	}
+}
+
+func TestInvalidParameters(t *testing.T) {
+	if _, err := NewGCSFilter(items(0, 10), 0, BasicM, testKey); err == nil {
+		t.Error("expected an error for p = 0")
+	}
+	if _, err := NewGCSFilter(items(0, 10), maxP+1, BasicM, testKey); err == nil {
+		t.Error("expected an error for p > 32")
+	}
+	if _, err := NewGCSFilter(items(0, 10), BasicP, 0, testKey); err == nil {
+		t.Error("expected an error for m = 0")
+	}
+}
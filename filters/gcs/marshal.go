@@ -0,0 +1,154 @@
+package gcs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Binary layout produced by MarshalBinary:
+//
+//	version  (1 byte)
+//	P        (1 byte)
+//	M        (unsigned varint)
+//	N        (Bitcoin CompactSize)
+//	data     (Golomb-Rice coded deltas, padded with zero bits to a whole byte)
+//
+// Everything after M is exactly the BIP-158 serialization of the filter, see BIP158.
+// The SipHash key is not encoded: BIP-158 derives it from the block hash,
+// and other users must share it out of band.
+const encodingVersion byte = 1
+
+// BIP158 returns the filter serialized as in BIP-158, i.e. N as a CompactSize
+// followed by the coded deltas.
+func (g *GCS) BIP158() []byte {
+	out := appendCompactSize(make([]byte, 0, 9+len(g.data)), uint64(g.N))
+	return append(out, g.data...)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+//
+// The result is a compact, versioned encoding of the filter which can be
+// restored with UnmarshalBinary.
+func (g *GCS) MarshalBinary() ([]byte, error) {
+	if g == nil || g.M == 0 {
+		return nil, errors.New("gcs: nil filter")
+	}
+	out := make([]byte, 0, 2+binary.MaxVarintLen64+9+len(g.data))
+	out = append(out, encodingVersion, g.P)
+	out = binary.AppendUvarint(out, g.M)
+	return append(out, g.BIP158()...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+//
+// It restores a filter produced by MarshalBinary, keeping the key the receiver was created with.
+// It returns an error if the data is truncated, has trailing bytes, or isn't a canonical encoding
+// of N sorted values below N*M.
+func (g *GCS) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return errors.New("gcs: data too short")
+	}
+	if data[0] != encodingVersion {
+		return fmt.Errorf("gcs: unsupported encoding version %d", data[0])
+	}
+	p := data[1]
+	if p == 0 || p > maxP {
+		return fmt.Errorf("gcs: invalid parameter p %d", p)
+	}
+	data = data[2:]
+
+	m, k := binary.Uvarint(data)
+	if k <= 0 || m == 0 {
+		return errors.New("gcs: invalid parameter m")
+	}
+	data = data[k:]
+
+	n, k := readCompactSize(data)
+	if k <= 0 || n > math.MaxUint32 {
+		return errors.New("gcs: invalid number of items")
+	}
+	data = data[k:]
+	// every value takes at least p+1 bits
+	if n > uint64(len(data))*8 {
+		return errors.New("gcs: data too short for the number of items")
+	}
+	if overflows(uint32(n), m) {
+		return errors.New("gcs: n * m overflows")
+	}
+
+	// decode every value, to check that they are in range and that the encoding is canonical
+	r := bitReader{data: data}
+	var value uint64
+	for i := uint64(0); i < n; i++ {
+		delta, ok := r.readDelta(uint(p))
+		if !ok {
+			return errors.New("gcs: truncated data")
+		}
+		next := value + delta
+		if next < value || next >= n*m {
+			return errors.New("gcs: value out of range")
+		}
+		value = next
+	}
+	if (r.pos+7)/8 != uint(len(data)) {
+		return errors.New("gcs: trailing data")
+	}
+	if r.pos%8 != 0 && data[len(data)-1]&(0xff>>(r.pos%8)) != 0 {
+		return errors.New("gcs: invalid padding")
+	}
+
+	g.P = p
+	g.M = m
+	g.N = uint32(n)
+	g.data = append([]byte{}, data...)
+	return nil
+}
+
+// appendCompactSize appends v in Bitcoin's variable length integer encoding.
+func appendCompactSize(buf []byte, v uint64) []byte {
+	switch {
+	case v < 0xfd:
+		return append(buf, byte(v))
+	case v <= math.MaxUint16:
+		return binary.LittleEndian.AppendUint16(append(buf, 0xfd), uint16(v))
+	case v <= math.MaxUint32:
+		return binary.LittleEndian.AppendUint32(append(buf, 0xfe), uint32(v))
+	default:
+		return binary.LittleEndian.AppendUint64(append(buf, 0xff), v)
+	}
+}
+
+// readCompactSize reads a canonical CompactSize, and returns the number of bytes read,
+// or 0 if data is invalid.
+func readCompactSize(data []byte) (uint64, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	var v, min uint64
+	var size int
+	switch data[0] {
+	case 0xfd:
+		size, min = 3, 0xfd
+		if len(data) >= size {
+			v = uint64(binary.LittleEndian.Uint16(data[1:]))
+		}
+	case 0xfe:
+		size, min = 5, math.MaxUint16+1
+		if len(data) >= size {
+			v = uint64(binary.LittleEndian.Uint32(data[1:]))
+		}
+	case 0xff:
+		size, min = 9, math.MaxUint32+1
+		if len(data) >= size {
+			v = binary.LittleEndian.Uint64(data[1:])
+		}
+	default:
+		return uint64(data[0]), 1
+	}
+	if len(data) < size || v < min {
+		return 0, 0
+	}
+	return v, size
+}
@@ -0,0 +1,104 @@
+package gcs
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	g, err := NewGCSFilter(items(0, 100), BasicP, BasicM, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := g.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g2 := EmptyGCSFilter(testKey)
+	if err := g2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if g2.P != g.P || g2.M != g.M || g2.N != g.N {
+		t.Fatalf("parameters differ after round trip: %+v", g2)
+	}
+	for _, item := range items(0, 100) {
+		if !g2.Test(item) {
+			t.Errorf("%s missing after round trip", item)
+		}
+	}
+
+	data2, err := g2.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, data2) {
+		t.Error("encoding is not stable across a round trip")
+	}
+
+	// with another key, the filter decodes but doesn't match the items
+	g3 := EmptyGCSFilter([KeySize]byte{})
+	if err := g3.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if g3.Test(items(0, 1)[0]) {
+		t.Error("item found with the wrong key")
+	}
+}
+
+func TestCompactSize(t *testing.T) {
+	for _, v := range []uint64{0, 0xfc, 0xfd, 0xffff, 0x10000, 0xffffffff, 0x100000000} {
+		data := appendCompactSize(nil, v)
+		got, n := readCompactSize(data)
+		if got != v || n != len(data) {
+			t.Errorf("compact size %d decoded as %d (%d bytes)", v, got, n)
+		}
+	}
+	// non canonical encodings are rejected
+	if _, n := readCompactSize([]byte{0xfd, 0x10, 0}); n != 0 {
+		t.Error("non canonical compact size accepted")
+	}
+}
+
+func TestUnmarshalCorrupt(t *testing.T) {
+	g, err := NewGCSFilter(items(0, 10), BasicP, BasicM, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := g.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// version, P, M (3 bytes), N
+	const nOffset = 5
+
+	withByte := func(i int, b byte) []byte {
+		out := append([]byte{}, data...)
+		out[i] = b
+		return out
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"nil", nil},
+		{"version", withByte(0, encodingVersion+1)},
+		{"zero p", withByte(1, 0)},
+		{"large p", withByte(1, maxP+1)},
+		{"header only", data[:nOffset]},
+		{"truncated", data[:len(data)-1]},
+		{"trailing", append(append([]byte{}, data...), 0)},
+		{"more items", withByte(nOffset, 11)},
+		{"fewer items", withByte(nOffset, 9)},
+		{"padding", withByte(len(data)-1, data[len(data)-1]|1)},
+		{"out of range", append(append([]byte{}, data[:nOffset+1]...), 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := new(GCS).UnmarshalBinary(tt.data); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+// Package hasher provides the hash functions the filters use to derive bucket indices,
+// bit positions and fingerprints.
+//
+// Every hash can be used as is, or keyed with a per-wallet secret. With a keyed hash, a filter
+// embedded on-chain reveals nothing to someone who doesn't know the key: they can't test
+// arbitrary public keys against it, nor craft items that collide with its entries.
+package hasher
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/zeebo/blake3"
+)
+
+// ID identifies a hash function in serialized filters.
+type ID byte
+
+const (
+	// SHA1 is the hash the cuckoo filter has always used, and remains the default.
+	SHA1 ID = 0
+	// SHA256 is keyed using HMAC.
+	SHA256 ID = 1
+	// BLAKE3 is keyed using BLAKE3's native keyed mode.
+	BLAKE3 ID = 2
+	// FNV64 is the 64-bit FNV-1a hash, followed by the MurmurHash3 finalizer so that every
+	// output bit depends on every input bit. It is fast but not cryptographic, and can't be keyed.
+	FNV64 ID = 3
+	// SipHash is SipHash-2-4, a fast 64-bit keyed hash. It requires a key.
+	SipHash ID = 4
+)
+
+// String implements fmt.Stringer.
+func (id ID) String() string {
+	switch id {
+	case SHA1:
+		return "sha1"
+	case SHA256:
+		return "sha256"
+	case BLAKE3:
+		return "blake3"
+	case FNV64:
+		return "fnv64"
+	case SipHash:
+		return "siphash"
+	default:
+		return fmt.Sprintf("hash(%d)", byte(id))
+	}
+}
+
+// Size returns the length of the digests produced by this hash function.
+func (id ID) Size() int {
+	switch id {
+	case SHA1:
+		return sha1.Size
+	case SHA256:
+		return sha256.Size
+	case BLAKE3:
+		return 32
+	case FNV64, SipHash:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// ErrNotKeyable is returned when asking for a keyed version of FNV64.
+var ErrNotKeyable = errors.New("hasher: hash function cannot be keyed")
+
+// Hasher computes digests with a given hash function, optionally keyed.
+//
+// A Hasher doesn't keep any state between calls to Sum, so it is safe
+// to share between goroutines.
+type Hasher struct {
+	id  ID
+	key []byte
+}
+
+// Default is the unkeyed SHA-1 hasher.
+var Default = &Hasher{id: SHA1}
+
+// New returns a Hasher for the hash function id.
+//
+// If key is not empty, the hasher is keyed, and digests can only be computed by someone who knows the key.
+// Keys of any length are accepted: BLAKE3 and SipHash keys of the wrong size are derived from key
+// using BLAKE3's key derivation mode.
+func New(id ID, key []byte) (*Hasher, error) {
+	switch id {
+	case SHA1, SHA256, BLAKE3:
+	case FNV64:
+		if len(key) > 0 {
+			return nil, ErrNotKeyable
+		}
+	case SipHash:
+		if len(key) == 0 {
+			return nil, errors.New("hasher: siphash requires a key")
+		}
+	default:
+		return nil, fmt.Errorf("hasher: unknown hash function %d", byte(id))
+	}
+
+	h := &Hasher{id: id}
+	switch {
+	case len(key) == 0:
+	case id == BLAKE3 && len(key) != 32:
+		h.key = DeriveKey("blake3 key", key)
+	case id == SipHash && len(key) != 16:
+		h.key = DeriveKey("siphash key", key)[:16]
+	default:
+		h.key = append([]byte{}, key...)
+	}
+	return h, nil
+}
+
+// ID returns the hash function used by this Hasher.
+func (h *Hasher) ID() ID {
+	return h.id
+}
+
+// Keyed returns true if this Hasher uses a secret key.
+func (h *Hasher) Keyed() bool {
+	return len(h.key) > 0
+}
+
+// Equal returns true if both hashers compute the same digests,
+// i.e. they use the same hash function and key.
+func (h *Hasher) Equal(other *Hasher) bool {
+	return h.id == other.id && subtle.ConstantTimeCompare(h.key, other.key) == 1
+}
+
+// Size returns the length of the digests returned by Sum.
+func (h *Hasher) Size() int {
+	return h.id.Size()
+}
+
+// Sum returns the digest of data, which is at least 8 bytes long.
+func (h *Hasher) Sum(data []byte) []byte {
+	switch h.id {
+	case SHA256:
+		if h.Keyed() {
+			mac := hmac.New(sha256.New, h.key)
+			_, _ = mac.Write(data)
+			return mac.Sum(nil)
+		}
+		sum := sha256.Sum256(data)
+		return sum[:]
+	case BLAKE3:
+		if h.Keyed() {
+			keyed, _ := blake3.NewKeyed(h.key)
+			_, _ = keyed.Write(data)
+			return keyed.Sum(nil)
+		}
+		sum := blake3.Sum256(data)
+		return sum[:]
+	case FNV64:
+		f := fnv.New64a()
+		_, _ = f.Write(data)
+		out := make([]byte, 8)
+		binary.BigEndian.PutUint64(out, fmix64(f.Sum64()))
+		return out
+	case SipHash:
+		return sipHash24(h.key, data)
+	default:
+		if h.Keyed() {
+			mac := hmac.New(sha1.New, h.key)
+			_, _ = mac.Write(data)
+			return mac.Sum(nil)
+		}
+		sum := sha1.Sum(data)
+		return sum[:]
+	}
+}
+
+// fmix64 is the 64-bit finalizer of MurmurHash3.
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
+
+// deriveKeyContext is used for domain separation of the keys derived by DeriveKey.
+const deriveKeyContext = "github.com/taurusgroup/multi-party-sig/filters 2024-01-10 filter key: "
+
+// DeriveKey derives a 32 byte filter key from some secret material, such as
+// a config's RID or ChainKey, and a purpose string.
+//
+// Different purposes give independent keys, so the same wallet secret can key several filters.
+func DeriveKey(purpose string, material ...[]byte) []byte {
+	out := make([]byte, 32)
+	var buf []byte
+	for _, m := range material {
+		buf = append(buf, m...)
+	}
+	blake3.DeriveKey(deriveKeyContext+purpose, buf, out)
+	return out
+}
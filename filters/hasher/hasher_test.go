@@ -0,0 +1,91 @@
+package hasher
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSipHash24(t *testing.T) {
+	// Test vectors from the reference implementation,
+	// with key 00 01 ... 0f and messages 00 01 ... (n-1).
+	key := make([]byte, 16)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "310e0edd47db6f72"},
+		{1, "fd67dc93c539f874"},
+		{15, "e545be4961ca29a1"},
+		{63, "724506eb4c328a95"},
+	}
+	for _, tt := range tests {
+		msg := make([]byte, tt.n)
+		for i := range msg {
+			msg[i] = byte(i)
+		}
+		if got := hex.EncodeToString(sipHash24(key, msg)); got != tt.want {
+			t.Errorf("siphash of %d bytes is %s, expected %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestHasher(t *testing.T) {
+	data := []byte("02a1633cafcc01ebfb6d78e39f687a1f0995c62fc95f51ead10a02ee0be551b5dc")
+	for _, id := range []ID{SHA1, SHA256, BLAKE3, FNV64, SipHash} {
+		var key []byte
+		if id == SipHash {
+			key = []byte("key")
+		}
+		h, err := New(id, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := h.Sum(data)
+		if len(sum) != id.Size() || len(sum) < 8 {
+			t.Errorf("%s: digest has length %d", id, len(sum))
+		}
+		if !bytes.Equal(sum, h.Sum(data)) {
+			t.Errorf("%s: digest is not deterministic", id)
+		}
+	}
+}
+
+func TestHasherKeyed(t *testing.T) {
+	data := []byte("hello")
+	for _, id := range []ID{SHA1, SHA256, BLAKE3, SipHash} {
+		h1, err := New(id, DeriveKey("test", []byte("wallet 1")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		h2, err := New(id, DeriveKey("test", []byte("wallet 2")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !h1.Keyed() {
+			t.Errorf("%s: expected a keyed hasher", id)
+		}
+		if bytes.Equal(h1.Sum(data), h2.Sum(data)) {
+			t.Errorf("%s: different keys give the same digest", id)
+		}
+		if id != SipHash {
+			plain, _ := New(id, nil)
+			if bytes.Equal(h1.Sum(data), plain.Sum(data)) {
+				t.Errorf("%s: keyed digest matches the unkeyed one", id)
+			}
+		}
+	}
+
+	if _, err := New(FNV64, []byte("key")); err != ErrNotKeyable {
+		t.Errorf("expected ErrNotKeyable, got %v", err)
+	}
+	if _, err := New(SipHash, nil); err == nil {
+		t.Error("siphash without a key should fail")
+	}
+	if _, err := New(ID(42), nil); err == nil {
+		t.Error("unknown hash should fail")
+	}
+}
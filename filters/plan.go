@@ -0,0 +1,128 @@
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Common byte budgets for a filter embedded in a transaction.
+const (
+	// OpReturnBudget is the largest payload of a standard Bitcoin OP_RETURN output.
+	OpReturnBudget = 80
+	// calldataGasPerByte is the gas cost of a non-zero calldata byte since EIP-2028.
+	calldataGasPerByte = 16
+)
+
+// CalldataBudget returns the number of calldata bytes which can be paid for with gas,
+// assuming every byte is non-zero, which is the worst case for a filter.
+func CalldataBudget(gas uint64) int {
+	return int(gas / calldataGasPerByte)
+}
+
+// ErrOverBudget is returned by Plan when no filter fits the byte budget.
+var ErrOverBudget = errors.New("filters: no filter fits the budget")
+
+// Plan describes a filter configuration, along with its predicted characteristics.
+type Plan struct {
+	// Name is the name the filter is registered as.
+	Name string
+	// N is the number of items the filter holds.
+	N uint
+	// E is the false positive rate to create the filter with, see New and Build.
+	E float64
+	// Size is the predicted size of the encoded filter, in bytes.
+	Size int
+	// FalsePositiveRate is the predicted false positive rate once the filter holds N items.
+	// It can differ from E, since filters round their parameters.
+	FalsePositiveRate float64
+}
+
+// String returns a short description of p.
+func (p Plan) String() string {
+	return fmt.Sprintf("%s(n=%d, e=%g): %d bytes, false positive rate %.3g", p.Name, p.N, p.E, p.Size, p.FalsePositiveRate)
+}
+
+// Plans returns a Plan for every registered filter with an Estimator, holding n items
+// with false positive rate e, sorted by increasing size.
+//
+// Only the filters whose package was imported are registered, so the result depends on
+// the imports of the program. Import package filters/all to consider every built-in filter.
+func Plans(n uint, e float64) []Plan {
+	registryMu.RLock()
+	plans := make([]Plan, 0, len(estimators))
+	for name, estimate := range estimators {
+		size, rate := estimate(n, e)
+		plans = append(plans, Plan{Name: name, N: n, E: e, Size: size, FalsePositiveRate: rate})
+	}
+	registryMu.RUnlock()
+	sortPlans(plans)
+	return plans
+}
+
+// PlanBudget returns the best filter for n items which fits in budget bytes.
+//
+// If some filters reach the target false positive rate e within budget, the smallest of them
+// is returned, leaving as much room as possible for the rest of the transaction.
+// Otherwise, the rate of every filter is relaxed by factors of 2 until it fits, and the plan
+// with the lowest predicted rate is returned: callers must then check whether its
+// FalsePositiveRate is acceptable.
+//
+// ErrOverBudget is returned if no filter fits even with a false positive rate of 1/2,
+// or of e if it is larger.
+func PlanBudget(n uint, e float64, budget int) (Plan, error) {
+	var met, relaxed []Plan
+	for _, p := range Plans(n, e) {
+		if p.Size <= budget && p.FalsePositiveRate <= e {
+			met = append(met, p)
+			continue
+		}
+		if relaxedPlan, ok := relax(p, budget); ok {
+			relaxed = append(relaxed, relaxedPlan)
+		}
+	}
+	if len(met) > 0 {
+		// Plans are sorted by size
+		return met[0], nil
+	}
+	if len(relaxed) == 0 {
+		return Plan{}, fmt.Errorf("%w of %d bytes for %d items", ErrOverBudget, budget, n)
+	}
+	sort.SliceStable(relaxed, func(i, j int) bool {
+		return relaxed[i].FalsePositiveRate < relaxed[j].FalsePositiveRate
+	})
+	return relaxed[0], nil
+}
+
+// relax doubles the false positive rate of p, up to 1/2, until the filter fits in budget bytes.
+//
+// The rate p.E is always tried first, even above 1/2, since a filter can fit in budget
+// without meeting its target rate, because of rounding.
+func relax(p Plan, budget int) (Plan, bool) {
+	registryMu.RLock()
+	estimate := estimators[p.Name]
+	registryMu.RUnlock()
+	for e := p.E; ; e = math.Min(2*e, 0.5) {
+		size, rate := estimate(p.N, e)
+		if size <= budget {
+			return Plan{Name: p.Name, N: p.N, E: e, Size: size, FalsePositiveRate: rate}, true
+		}
+		if e <= 0 || e >= 0.5 {
+			return Plan{}, false
+		}
+	}
+}
+
+func sortPlans(plans []Plan) {
+	sort.Slice(plans, func(i, j int) bool {
+		a, b := plans[i], plans[j]
+		if a.Size != b.Size {
+			return a.Size < b.Size
+		}
+		if a.FalsePositiveRate != b.FalsePositiveRate {
+			return a.FalsePositiveRate < b.FalsePositiveRate
+		}
+		return a.Name < b.Name
+	})
+}
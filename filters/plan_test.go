@@ -0,0 +1,94 @@
+package filters_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/taurusgroup/multi-party-sig/filters"
+)
+
+func TestEstimators(t *testing.T) {
+	for _, n := range []uint{0, 1, 3, 100, 5000} {
+		items := make([]string, n)
+		for i := range items {
+			items[i] = fmt.Sprintf("item-%d", i)
+		}
+		for _, e := range []float64{0.1, 0.001} {
+			plans := filters.Plans(n, e)
+			if len(plans) != len(filters.Names()) {
+				t.Fatalf("found %d plans for %d filters", len(plans), len(filters.Names()))
+			}
+			for _, p := range plans {
+				f, err := filters.Build(p.Name, items, e)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := f.MarshalBinary()
+				if err != nil {
+					t.Fatal(err)
+				}
+				// the size of a Golomb-coded set depends on the items
+				if p.Name == "gcs" {
+					if math.Abs(float64(p.Size-len(data))) > 2+0.02*float64(len(data)) {
+						t.Errorf("%v: actual size is %d", p, len(data))
+					}
+					continue
+				}
+				if p.Size != len(data) {
+					t.Errorf("%v: actual size is %d", p, len(data))
+				}
+				if p.FalsePositiveRate > 1.05*e {
+					t.Errorf("%v: rate above target", p)
+				}
+			}
+		}
+	}
+}
+
+func TestPlanBudget(t *testing.T) {
+	p, err := filters.PlanBudget(3, 0.01, filters.OpReturnBudget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Size > filters.OpReturnBudget || p.FalsePositiveRate > 0.01 {
+		t.Errorf("%v doesn't meet the target", p)
+	}
+	for _, other := range filters.Plans(3, 0.01) {
+		if other.FalsePositiveRate <= 0.01 && other.Size < p.Size {
+			t.Errorf("%v is smaller than %v", other, p)
+		}
+	}
+
+	// 100 items can't fit in 80 bytes with a rate of 0.0001
+	p, err = filters.PlanBudget(100, 0.0001, filters.OpReturnBudget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Size > filters.OpReturnBudget || p.FalsePositiveRate <= 0.0001 {
+		t.Errorf("unexpected plan %v", p)
+	}
+
+	// gas for 1000 non-zero bytes
+	p, err = filters.PlanBudget(100, 0.0001, filters.CalldataBudget(16000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Size > 1000 || p.FalsePositiveRate > 0.0001 {
+		t.Errorf("%v doesn't meet the target", p)
+	}
+
+	// a Bloom filter of 100 items rounds e = 0.6 to 0.607, but still fits in 25 bytes
+	p, err = filters.PlanBudget(100, 0.6, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Size > 25 || p.E != 0.6 {
+		t.Errorf("unexpected plan %v", p)
+	}
+
+	if _, err := filters.PlanBudget(10, 0.01, 5); !errors.Is(err, filters.ErrOverBudget) {
+		t.Errorf("expected ErrOverBudget, got %v", err)
+	}
+}
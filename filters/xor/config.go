@@ -0,0 +1,48 @@
+package xor
+
+import (
+	"fmt"
+
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+	"github.com/taurusgroup/multi-party-sig/protocols/cmp/config"
+	"github.com/taurusgroup/multi-party-sig/protocols/frost/keygen"
+)
+
+// NewFromConfig builds a filter of the public key shares of every party in a cmp.Config.
+//
+// Each item is the binary encoding of a party's ECDSA public share,
+// i.e. a compressed point for secp256k1.
+func NewFromConfig(c *config.Config, opts ...Option) (*Xor, error) {
+	points := make(map[party.ID]curve.Point, len(c.Public))
+	for id, public := range c.Public {
+		points[id] = public.ECDSA
+	}
+	return newFromPoints(points, opts)
+}
+
+// NewFromFrostConfig builds a filter of the verification shares of every party in a frost.Config.
+func NewFromFrostConfig(c *keygen.Config, opts ...Option) (*Xor, error) {
+	return newFromPoints(c.VerificationShares.Points, opts)
+}
+
+// NewFromTaprootConfig builds a filter of the verification shares of every party in a frost.TaprootConfig.
+func NewFromTaprootConfig(c *keygen.TaprootConfig, opts ...Option) (*Xor, error) {
+	points := make(map[party.ID]curve.Point, len(c.VerificationShares))
+	for id, point := range c.VerificationShares {
+		points[id] = point
+	}
+	return newFromPoints(points, opts)
+}
+
+func newFromPoints(points map[party.ID]curve.Point, opts []Option) (*Xor, error) {
+	items := make([]string, 0, len(points))
+	for id, point := range points {
+		data, err := point.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("xor: party %s: %w", id, err)
+		}
+		items = append(items, string(data))
+	}
+	return NewXorFilter(items, opts...)
+}
@@ -0,0 +1,50 @@
+package xor
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/taurusgroup/multi-party-sig/internal/test"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+	"github.com/taurusgroup/multi-party-sig/pkg/pool"
+	"github.com/taurusgroup/multi-party-sig/protocols/frost/keygen"
+)
+
+func TestNewFromConfig(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+
+	configs, partyIDs := test.GenerateConfig(curve.Secp256k1{}, 3, 1, rand.Reader, pl)
+	c := configs[partyIDs[0]]
+
+	x, err := NewFromConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	points := make(map[party.ID]curve.Point, len(partyIDs))
+	for _, id := range partyIDs {
+		points[id] = c.Public[id].ECDSA
+		data, _ := c.Public[id].ECDSA.MarshalBinary()
+		if !x.Test(string(data)) {
+			t.Errorf("public share of %s missing", id)
+		}
+	}
+
+	// a frost config with the same shares gives the same filter
+	frostConfig := &keygen.Config{
+		ID:                 c.ID,
+		Threshold:          c.Threshold,
+		VerificationShares: party.NewPointMap(points),
+	}
+	y, err := NewFromFrostConfig(frostConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataX, _ := x.MarshalBinary()
+	dataY, _ := y.MarshalBinary()
+	if string(dataX) != string(dataY) {
+		t.Error("cmp and frost configs with the same shares gave different filters")
+	}
+}
@@ -0,0 +1,118 @@
+package xor
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/taurusgroup/multi-party-sig/filters"
+)
+
+func init() {
+	filters.Register("xor", func(n uint, e float64) filters.Filter {
+		return NewBuilder(n, WithFingerprintLength(FingerprintLength(e)))
+	})
+	filters.RegisterEstimator("xor", Estimate)
+	filters.RegisterDecoder("xor", decode)
+}
+
+var _ filters.Filter = (*Builder)(nil)
+
+// Builder collects items, and builds a Xor filter from them when it is queried or serialized.
+//
+// It lets the immutable Xor filter be used through the filters.Filter interface,
+// alongside filters which are built incrementally.
+type Builder struct {
+	items  []string
+	seen   map[string]struct{}
+	opts   []Option
+	filter *Xor
+	// decoded is set for builders of a decoded filter, whose items are unknown
+	decoded bool
+}
+
+// NewBuilder returns a Builder expecting about n items, which builds filters with opts.
+func NewBuilder(n uint, opts ...Option) *Builder {
+	return &Builder{
+		items: make([]string, 0, n),
+		seen:  make(map[string]struct{}, n),
+		opts:  opts,
+	}
+}
+
+// ErrDecoded is returned when adding items to a decoded filter.
+var ErrDecoded = errors.New("xor: can't add items to a decoded filter")
+
+// Add implements filters.Filter.
+//
+// The filter is rebuilt on the next call to Test or MarshalBinary.
+// Items can't be added to a filter restored by filters.Decode, since the others are unknown.
+func (b *Builder) Add(item string) error {
+	if b.decoded {
+		return ErrDecoded
+	}
+	if _, ok := b.seen[item]; ok {
+		return nil
+	}
+	b.seen[item] = struct{}{}
+	b.items = append(b.items, item)
+	b.filter = nil
+	return nil
+}
+
+// Build returns the filter of all the items added so far.
+func (b *Builder) Build() (*Xor, error) {
+	if b.filter == nil {
+		x, err := NewXorFilter(b.items, b.opts...)
+		if err != nil {
+			return nil, err
+		}
+		b.filter = x
+	}
+	return b.filter, nil
+}
+
+// Test implements filters.Filter.
+//
+// It returns false if the filter can't be built, which only happens with negligible probability.
+func (b *Builder) Test(item string) bool {
+	x, err := b.Build()
+	return err == nil && x.Test(item)
+}
+
+// Len implements filters.Filter, it returns the number of distinct items added.
+func (b *Builder) Len() uint {
+	if b.decoded {
+		return b.filter.N
+	}
+	return uint(len(b.items))
+}
+
+// MarshalBinary implements filters.Filter, using the encoding of Xor.
+func (b *Builder) MarshalBinary() ([]byte, error) {
+	x, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return x.MarshalBinary()
+}
+
+// Estimate returns the encoded size and false positive rate of a filter of n distinct items,
+// with fingerprints of FingerprintLength(e) bits.
+func Estimate(n uint, e float64) (size int, rate float64) {
+	x := &Xor{F: FingerprintLength(e)}
+	x.dimensions(uint32(n))
+	size = 12 + int(x.slots()*x.F/8)
+	for _, v := range []uint64{uint64(x.SegmentLength), uint64(x.SegmentCount), uint64(x.N)} {
+		size += len(binary.AppendUvarint(nil, v))
+	}
+	return size, x.FalsePositiveRate()
+}
+
+// decode implements filters.Decoder, returning a Builder of the decoded filter.
+func decode(data []byte) (filters.Filter, error) {
+	x := EmptyXorFilter()
+	if err := x.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return &Builder{filter: x, decoded: true}, nil
+}
@@ -0,0 +1,139 @@
+package xor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+// Binary layout produced by MarshalBinary:
+//
+//	version  (1 byte)
+//	hash id  (1 byte, see hasher.ID)
+//	flags    (1 byte, bit 1 set for a keyed hash)
+//	F        (1 byte)
+//	seed     (8 bytes, big-endian)
+//	SegmentLength, SegmentCount, N (unsigned varints)
+//	fingerprints ((SegmentCount + 2) * SegmentLength * F/8 bytes)
+//
+// Since NewXorFilter is deterministic, two filters built from the same set of items
+// always serialize to the same bytes.
+const (
+	// encodingVersion is bumped whenever the layout above changes.
+	encodingVersion byte = 1
+
+	// flagKeyed is set in the flags byte when the hash is keyed, like for the other filters.
+	// The key itself is never encoded.
+	flagKeyed byte = 1 << 1
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+//
+// The result is a compact, versioned encoding of the filter which can be
+// restored with UnmarshalBinary.
+func (x *Xor) MarshalBinary() ([]byte, error) {
+	if x == nil || x.fingerprints == nil {
+		return nil, errors.New("xor: nil filter")
+	}
+
+	var flags byte
+	if x.hasher.Keyed() {
+		flags |= flagKeyed
+	}
+
+	out := make([]byte, 0, 12+3*binary.MaxVarintLen64+len(x.fingerprints))
+	out = append(out, encodingVersion, byte(x.hasher.ID()), flags, byte(x.F))
+	out = binary.BigEndian.AppendUint64(out, x.Seed)
+	for _, v := range []uint64{uint64(x.SegmentLength), uint64(x.SegmentCount), uint64(x.N)} {
+		out = binary.AppendUvarint(out, v)
+	}
+	out = append(out, x.fingerprints...)
+	return out, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+//
+// It restores a filter produced by MarshalBinary, and returns an error
+// if the data is truncated, has trailing bytes, or describes an invalid filter.
+//
+// A keyed filter can only be decoded into a Xor created by EmptyXorFilter
+// with a hasher using the same hash function and key.
+func (x *Xor) UnmarshalBinary(data []byte) error {
+	if len(data) < 12 {
+		return errors.New("xor: data too short")
+	}
+	if data[0] != encodingVersion {
+		return fmt.Errorf("xor: unsupported encoding version %d", data[0])
+	}
+	flags := data[2]
+	if flags&^flagKeyed != 0 {
+		return fmt.Errorf("xor: unknown flags %#x", flags)
+	}
+	f := uint(data[3])
+	if f != 8 && f != 16 {
+		return fmt.Errorf("xor: invalid fingerprint length %d", f)
+	}
+
+	h, err := decodeHasher(x.hasher, hasher.ID(data[1]), flags&flagKeyed != 0)
+	if err != nil {
+		return err
+	}
+	seed := binary.BigEndian.Uint64(data[4:12])
+	data = data[12:]
+
+	var params [3]uint64
+	for i := range params {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("xor: invalid header")
+		}
+		params[i] = v
+		data = data[n:]
+	}
+	segmentLength, segmentCount, n := params[0], params[1], params[2]
+
+	if segmentLength < 4 || segmentLength > maxSegmentLength || segmentLength&(segmentLength-1) != 0 {
+		return fmt.Errorf("xor: invalid segment length %d", segmentLength)
+	}
+	// bounding the segment count by the data length first prevents overflows
+	if segmentCount == 0 || segmentCount > uint64(len(data)) {
+		return fmt.Errorf("xor: invalid segment count %d", segmentCount)
+	}
+	if expected := (segmentCount + arity - 1) * segmentLength * uint64(f/8); uint64(len(data)) != expected {
+		return fmt.Errorf("xor: expected %d bytes of fingerprints, found %d", expected, len(data))
+	}
+
+	*x = Xor{
+		Seed:          seed,
+		SegmentLength: uint32(segmentLength),
+		SegmentCount:  uint32(segmentCount),
+		F:             f,
+		N:             uint(n),
+		hasher:        h,
+		fingerprints:  append([]byte{}, data...),
+	}
+	return nil
+}
+
+// decodeHasher returns the hasher to use for a decoded filter.
+//
+// Unkeyed hashers are fully described by their id, but keyed ones must be provided
+// beforehand as current, since we don't know the key.
+func decodeHasher(current *hasher.Hasher, id hasher.ID, keyed bool) (*hasher.Hasher, error) {
+	if !keyed {
+		h, err := hasher.New(id, nil)
+		if err != nil {
+			return nil, fmt.Errorf("xor: %w", err)
+		}
+		return h, nil
+	}
+	if current == nil || !current.Keyed() {
+		return nil, errors.New("xor: filter uses a keyed hash, decode it with EmptyXorFilter(WithHasher(h))")
+	}
+	if current.ID() != id {
+		return nil, fmt.Errorf("xor: filter uses %s, but hasher is %s", id, current.ID())
+	}
+	return current, nil
+}
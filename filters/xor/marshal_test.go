@@ -0,0 +1,112 @@
+package xor
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	for _, f := range []uint{8, 16} {
+		x, err := NewXorFilter(items(0, 100), WithFingerprintLength(f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := x.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		x2 := new(Xor)
+		if err := x2.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if x2.Seed != x.Seed || x2.SegmentLength != x.SegmentLength || x2.SegmentCount != x.SegmentCount || x2.F != f || x2.N != x.N {
+			t.Fatalf("parameters differ after round trip: %+v", x2)
+		}
+		for _, item := range items(0, 100) {
+			if !x2.Test(item) {
+				t.Errorf("%s missing after round trip", item)
+			}
+		}
+
+		data2, err := x2.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, data2) {
+			t.Error("encoding is not stable across a round trip")
+		}
+	}
+}
+
+func TestUnmarshalCorrupt(t *testing.T) {
+	x, err := NewXorFilter(items(0, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := x.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withByte := func(i int, b byte) []byte {
+		out := append([]byte{}, data...)
+		out[i] = b
+		return out
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"nil", nil},
+		{"version", withByte(0, encodingVersion+1)},
+		{"hash id", withByte(1, 0xff)},
+		{"flags", withByte(2, 0x80)},
+		{"fingerprint length", withByte(3, 12)},
+		{"header only", data[:13]},
+		{"truncated", data[:len(data)-1]},
+		{"trailing", append(append([]byte{}, data...), 0)},
+		{"segment length not power of 2", withByte(12, 5)},
+		{"zero segment count", withByte(13, 0)},
+		{"huge filter", []byte{encodingVersion, byte(hasher.SHA1), 0, 8, 0, 0, 0, 0, 0, 0, 0, 0, 4, 0xff, 0xff, 0xff, 0xff, 0x0f, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := new(Xor).UnmarshalBinary(tt.data); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestMarshalKeyed(t *testing.T) {
+	key := hasher.DeriveKey("xor test", []byte("wallet secret"))
+	h, err := hasher.New(hasher.BLAKE3, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := NewXorFilter([]string{"hello"}, WithHasher(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := x.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without the key, the filter can't be decoded
+	if err := new(Xor).UnmarshalBinary(data); err == nil {
+		t.Error("decoding a keyed filter without its key should fail")
+	}
+
+	x2 := EmptyXorFilter(WithHasher(h))
+	if err := x2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !x2.Test("hello") {
+		t.Error("hello missing after round trip")
+	}
+}
@@ -0,0 +1,385 @@
+// Based on:
+// https://arxiv.org/abs/2201.01174 (Binary Fuse Filters: Fast and Smaller Than Xor Filters)
+// https://github.com/FastFilter/xorfilter
+
+package xor
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/taurusgroup/multi-party-sig/filters/hasher"
+)
+
+// arity is the number of slots each item maps to.
+const arity = 3
+
+// maxSegmentLength bounds the segment length, and is checked when decoding a filter.
+const maxSegmentLength = 1 << 18
+
+// maxIterations is the number of seeds we try before giving up on building a filter.
+// Each attempt fails with probability well below 1% for large sets, so this is never reached in practice.
+const maxIterations = 1024
+
+// ErrBuildFailed is returned when no seed could be found to build a filter.
+var ErrBuildFailed = errors.New("xor: failed to build filter")
+
+// Xor is an immutable binary fuse filter.
+//
+// It is built once from the full set of items, and has a false positive rate of 2^-F.
+// It uses 1.125 F bits per item for large sets, up to about 1.25 F for ten thousand items,
+// so about 9 to 10 bits per item with the default F = 8. Items can't be added or removed afterwards.
+type Xor struct {
+	Seed          uint64 // seed of the item hash
+	SegmentLength uint32 // number of slots per segment, a power of 2
+	SegmentCount  uint32 // number of segments an item can start in
+	F             uint   // fingerprint length in bits, 8 or 16
+	N             uint   // number of distinct items
+
+	// hasher derives 64-bit keys from items
+	hasher *hasher.Hasher
+	// fingerprints holds (SegmentCount + 2) * SegmentLength fingerprints,
+	// each stored on F/8 bytes in little-endian order
+	fingerprints []byte
+}
+
+// Option enables an optional feature when building a filter.
+type Option func(*options)
+
+type options struct {
+	f      uint
+	hasher *hasher.Hasher
+}
+
+func newOptions(opts []Option) options {
+	o := options{f: 8, hasher: hasher.Default}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithFingerprintLength sets the fingerprint length f, which must be 8 (the default) or 16.
+// The false positive rate of the filter is 2^-f.
+func WithFingerprintLength(f uint) Option {
+	return func(o *options) {
+		o.f = f
+	}
+}
+
+// WithHasher replaces the default SHA-1 hash with h.
+//
+// With a keyed hasher, the filter can only be queried by someone who knows the key,
+// which must be passed again to EmptyXorFilter when decoding it.
+func WithHasher(h *hasher.Hasher) Option {
+	return func(o *options) {
+		o.hasher = h
+	}
+}
+
+// FingerprintLength returns the shortest supported fingerprint length
+// giving a false positive rate of at most e.
+func FingerprintLength(e float64) uint {
+	if e >= 1.0/256 {
+		return 8
+	}
+	return 16
+}
+
+// EmptyXorFilter returns a filter with no parameters, to be filled by UnmarshalBinary.
+//
+// The options are only needed to decode a filter built with a keyed hasher.
+func EmptyXorFilter(opts ...Option) *Xor {
+	o := newOptions(opts)
+	return &Xor{hasher: o.hasher}
+}
+
+// NewXorFilter builds a filter containing items.
+//
+// Items are deduplicated and sorted, and seeds are tried in a fixed sequence,
+// so the same set of items always gives the same filter, whatever their order.
+func NewXorFilter(items []string, opts ...Option) (*Xor, error) {
+	o := newOptions(opts)
+	if o.f != 8 && o.f != 16 {
+		return nil, errors.New("xor: fingerprint length must be 8 or 16")
+	}
+	x := &Xor{F: o.f, hasher: o.hasher}
+
+	keys := make([]uint64, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, x.key(item))
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	unique := keys[:0]
+	for i, k := range keys {
+		if i == 0 || k != keys[i-1] {
+			unique = append(unique, k)
+		}
+	}
+
+	if err := x.populate(unique); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// key returns the 64-bit key of an item.
+func (x *Xor) key(item string) uint64 {
+	return binary.BigEndian.Uint64(x.hasher.Sum([]byte(item)))
+}
+
+func segmentLength(size uint32) uint32 {
+	if size == 0 {
+		return 4
+	}
+	l := uint32(1) << int(math.Floor(math.Log(float64(size))/math.Log(3.33)+2.25))
+	if l > maxSegmentLength {
+		return maxSegmentLength
+	}
+	return l
+}
+
+func sizeFactor(size uint32) float64 {
+	return math.Max(1.125, 0.875+0.25*math.Log(1000000)/math.Log(float64(size)))
+}
+
+// initialize sets the dimensions of a filter for size keys, and allocates its fingerprints.
+func (x *Xor) initialize(size uint32) {
+	x.dimensions(size)
+	x.fingerprints = make([]byte, x.slots()*x.F/8)
+}
+
+// dimensions sets the segment length and count of a filter for size keys.
+func (x *Xor) dimensions(size uint32) {
+	x.SegmentLength = segmentLength(size)
+	capacity := uint32(0)
+	if size > 1 {
+		capacity = uint32(math.Round(float64(size) * sizeFactor(size)))
+	}
+	segments := (capacity + x.SegmentLength - 1) / x.SegmentLength
+	if segments <= arity-1 {
+		x.SegmentCount = 1
+	} else {
+		x.SegmentCount = segments - (arity - 1)
+	}
+	x.N = uint(size)
+}
+
+// slots returns the total number of fingerprints in the filter.
+func (x *Xor) slots() uint {
+	return uint(x.SegmentCount+arity-1) * uint(x.SegmentLength)
+}
+
+// indices returns the 3 slots of a hashed key.
+func (x *Xor) indices(hash uint64) (uint32, uint32, uint32) {
+	hi, _ := bits.Mul64(hash, uint64(x.SegmentCount)*uint64(x.SegmentLength))
+	mask := x.SegmentLength - 1
+	h0 := uint32(hi)
+	h1 := h0 + x.SegmentLength
+	h2 := h1 + x.SegmentLength
+	h1 ^= uint32(hash>>18) & mask
+	h2 ^= uint32(hash) & mask
+	return h0, h1, h2
+}
+
+func (x *Xor) fingerprint(hash uint64) uint16 {
+	f := hash ^ hash>>32
+	if x.F == 8 {
+		return uint16(f & 0xff)
+	}
+	return uint16(f)
+}
+
+func (x *Xor) get(i uint32) uint16 {
+	if x.F == 8 {
+		return uint16(x.fingerprints[i])
+	}
+	return binary.LittleEndian.Uint16(x.fingerprints[2*i:])
+}
+
+func (x *Xor) set(i uint32, v uint16) {
+	if x.F == 8 {
+		x.fingerprints[i] = byte(v)
+		return
+	}
+	binary.LittleEndian.PutUint16(x.fingerprints[2*i:], v)
+}
+
+// populate builds the filter from sorted, distinct keys.
+//
+// Every key is mapped to 3 slots, and we look for an order in which each key has a slot
+// no later key uses ("peeling"). Fingerprints are then assigned in reverse order,
+// so that the xor of the 3 slots of every key is its fingerprint.
+func (x *Xor) populate(keys []uint64) error {
+	size := uint32(len(keys))
+	x.initialize(size)
+	capacity := uint32(x.slots())
+
+	alone := make([]uint32, capacity)
+	// the 2 low bits hold the xor of the positions (0, 1, or 2) of the keys in the slot,
+	// and the others count them
+	t2count := make([]uint8, capacity)
+	t2hash := make([]uint64, capacity)
+	reverseH := make([]uint8, size)
+	reverseOrder := make([]uint64, size+1)
+	reverseOrder[size] = 1
+
+	blockBits := 1
+	for (uint32(1) << blockBits) < x.SegmentCount {
+		blockBits++
+	}
+	startPos := make([]uint32, 1<<blockBits)
+
+	var h012 [5]uint32
+	rng := uint64(1)
+	for iteration := 0; ; iteration++ {
+		if iteration == maxIterations {
+			return ErrBuildFailed
+		}
+		x.Seed = splitmix64(&rng)
+		for i := range reverseOrder[:size] {
+			reverseOrder[i] = 0
+		}
+		for i := range t2count {
+			t2count[i] = 0
+			t2hash[i] = 0
+		}
+
+		// sort the hashes by segment, which makes the next loop cache friendly
+		for i := range startPos {
+			startPos[i] = uint32((uint64(i) * uint64(size)) >> blockBits)
+		}
+		for _, key := range keys {
+			hash := mix(key, x.Seed)
+			segment := hash >> (64 - blockBits)
+			for reverseOrder[startPos[segment]] != 0 {
+				segment = (segment + 1) & (1<<blockBits - 1)
+			}
+			reverseOrder[startPos[segment]] = hash
+			startPos[segment]++
+		}
+
+		failed := false
+		for _, hash := range reverseOrder[:size] {
+			h0, h1, h2 := x.indices(hash)
+			t2count[h0] += 4
+			t2hash[h0] ^= hash
+			t2count[h1] += 4
+			t2count[h1] ^= 1
+			t2hash[h1] ^= hash
+			t2count[h2] += 4
+			t2count[h2] ^= 2
+			t2hash[h2] ^= hash
+			// a slot overflowed its 6 bit counter
+			if t2count[h0] < 4 || t2count[h1] < 4 || t2count[h2] < 4 {
+				failed = true
+			}
+		}
+		if failed {
+			continue
+		}
+
+		// queue the slots used by a single key
+		queued := 0
+		for i := uint32(0); i < capacity; i++ {
+			alone[queued] = i
+			if t2count[i]>>2 == 1 {
+				queued++
+			}
+		}
+		stacked := uint32(0)
+		for queued > 0 {
+			queued--
+			index := alone[queued]
+			if t2count[index]>>2 != 1 {
+				continue
+			}
+			hash := t2hash[index]
+			found := t2count[index] & 3
+			reverseH[stacked] = found
+			reverseOrder[stacked] = hash
+			stacked++
+
+			h0, h1, h2 := x.indices(hash)
+			h012[1], h012[2], h012[3], h012[4] = h1, h2, h0, h1
+			for j := uint8(1); j < arity; j++ {
+				other := h012[found+j]
+				alone[queued] = other
+				if t2count[other]>>2 == 2 {
+					queued++
+				}
+				t2count[other] -= 4
+				t2count[other] ^= mod3(found + j)
+				t2hash[other] ^= hash
+			}
+		}
+		if stacked == size {
+			break
+		}
+	}
+
+	for i := int(size) - 1; i >= 0; i-- {
+		hash := reverseOrder[i]
+		h0, h1, h2 := x.indices(hash)
+		found := reverseH[i]
+		h012[0], h012[1], h012[2], h012[3], h012[4] = h0, h1, h2, h0, h1
+		x.set(h012[found], x.fingerprint(hash)^x.get(h012[found+1])^x.get(h012[found+2]))
+	}
+	return nil
+}
+
+// Test returns true if needle may be in the filter,
+// and false if it definitely isn't.
+func (x *Xor) Test(needle string) bool {
+	hash := mix(x.key(needle), x.Seed)
+	h0, h1, h2 := x.indices(hash)
+	return x.fingerprint(hash)^x.get(h0)^x.get(h1)^x.get(h2) == 0
+}
+
+// Len returns the number of distinct items in the filter.
+func (x *Xor) Len() uint {
+	return x.N
+}
+
+// FalsePositiveRate returns the false positive rate 2^-F of the filter.
+func (x *Xor) FalsePositiveRate() float64 {
+	return math.Pow(2, -float64(x.F))
+}
+
+// BitsPerItem returns the size of the fingerprint table, in bits per item.
+func (x *Xor) BitsPerItem() float64 {
+	if x.N == 0 {
+		return 0
+	}
+	return float64(len(x.fingerprints)*8) / float64(x.N)
+}
+
+func mod3(x uint8) uint8 {
+	if x > 2 {
+		x -= 3
+	}
+	return x
+}
+
+// mix combines a key with the filter's seed, using the MurmurHash3 finalizer.
+func mix(key, seed uint64) uint64 {
+	h := key + seed
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// splitmix64 returns the next value of the sequence of seeds tried by populate.
+func splitmix64(state *uint64) uint64 {
+	*state += 0x9E3779B97F4A7C15
+	z := *state
+	z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+	z = (z ^ z>>27) * 0x94D049BB133111EB
+	return z ^ z>>31
+}
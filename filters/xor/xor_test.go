@@ -0,0 +1,105 @@
+package xor
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func items(from, to int) []string {
+	out := make([]string, 0, to-from)
+	for i := from; i < to; i++ {
+		out = append(out, fmt.Sprintf("0x%040d", i))
+	}
+	return out
+}
+
+func TestXor(t *testing.T) {
+	for _, f := range []uint{8, 16} {
+		for _, n := range []int{0, 1, 2, 10, 1000, 20000} {
+			x, err := NewXorFilter(items(0, n), WithFingerprintLength(f))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if x.Len() != uint(n) {
+				t.Errorf("length is %d, expected %d", x.Len(), n)
+			}
+			for _, item := range items(0, n) {
+				if !x.Test(item) {
+					t.Fatalf("f = %d, n = %d: %s missing", f, n, item)
+				}
+			}
+		}
+	}
+}
+
+func TestFalsePositiveRate(t *testing.T) {
+	x, err := NewXorFilter(items(0, 10000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	falsePositives := 0
+	for _, item := range items(10000, 110000) {
+		if x.Test(item) {
+			falsePositives++
+		}
+	}
+	// expect ~390
+	if falsePositives > 600 {
+		t.Errorf("%d false positives out of 100000", falsePositives)
+	}
+	// the size factor is 1.25 for 10000 items, and goes down to 1.125 for a million
+	if bits := x.BitsPerItem(); bits > 10.5 {
+		t.Errorf("filter uses %.2f bits per item", bits)
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	forward := items(0, 100)
+	backward := make([]string, 0, 110)
+	for i := len(forward) - 1; i >= 0; i-- {
+		backward = append(backward, forward[i])
+	}
+	// duplicates don't change the filter either
+	backward = append(backward, forward[:10]...)
+
+	a, err := NewXorFilter(forward)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewXorFilter(backward)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataA, _ := a.MarshalBinary()
+	dataB, _ := b.MarshalBinary()
+	if !bytes.Equal(dataA, dataB) {
+		t.Error("the order of items changed the filter")
+	}
+}
+
+func TestInvalidFingerprintLength(t *testing.T) {
+	if _, err := NewXorFilter(items(0, 10), WithFingerprintLength(12)); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	b := NewBuilder(10)
+	for _, item := range items(0, 10) {
+		_ = b.Add(item)
+	}
+	_ = b.Add(items(0, 1)[0])
+	if b.Len() != 10 {
+		t.Errorf("length is %d, expected 10", b.Len())
+	}
+	if !b.Test(items(0, 1)[0]) {
+		t.Error("item missing")
+	}
+
+	// adding an item rebuilds the filter
+	_ = b.Add("new item")
+	if !b.Test("new item") {
+		t.Error("new item missing")
+	}
+}
@@ -4,16 +4,20 @@ import (
 	"crypto/hmac"
 	"crypto/sha512"
 	"encoding/binary"
+	"errors"
 	"fmt"
 
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
 )
 
+// ErrInvalidIndex is returned by DeriveScalar for the rare indices which don't give a valid child.
+var ErrInvalidIndex = errors.New("bad index")
+
 // DeriveScalar uses a public point, chaining value, and index, to derive a scalar and chaining value.
 //
 // This scalar should be added to the secret key.
 //
-// If an error is returned, this means that this index will not be useable, and another
+// If ErrInvalidIndex is returned, this means that this index will not be useable, and another
 // index should be used instead.
 //
 // This function will panic if an index for a hardened key is used.
@@ -35,7 +39,7 @@ func DeriveScalar(public *curve.Secp256k1Point, chaining []byte, i uint32) (*cur
 	scalar := new(curve.Secp256k1Scalar)
 	err := scalar.UnmarshalBinary(out[:32])
 	if err != nil || scalar.IsZero() {
-		return nil, nil, fmt.Errorf("bad index: %d", i)
+		return nil, nil, fmt.Errorf("%w: %d", ErrInvalidIndex, i)
 	}
 
 	return scalar, out[32:], nil
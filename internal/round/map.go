@@ -0,0 +1,49 @@
+package round
+
+// MapResult returns a session behaving exactly like s, except that the result of its output round,
+// if it is ever reached, is replaced by f(result).
+//
+// This lets a protocol be reused with a richer result, without changing any of its rounds.
+func MapResult(s Session, f func(result interface{}) interface{}) Session {
+	switch r := s.(type) {
+	case nil:
+		return nil
+	case *Output:
+		return &Output{Helper: r.Helper, Result: f(r.Result)}
+	case *Abort:
+		return r
+	case BroadcastRound:
+		return &mappedBroadcast{mapped: mapped{Session: s, f: f}, broadcast: r}
+	default:
+		return &mapped{Session: s, f: f}
+	}
+}
+
+// mapped wraps a round, so that the rounds it returns are wrapped too.
+type mapped struct {
+	Session
+	f func(interface{}) interface{}
+}
+
+// Finalize implements Round.
+func (m *mapped) Finalize(out chan<- *Message) (Session, error) {
+	next, err := m.Session.Finalize(out)
+	return MapResult(next, m.f), err
+}
+
+// mappedBroadcast is a mapped round which still implements BroadcastRound,
+// since the handler relies on it to route broadcast messages.
+type mappedBroadcast struct {
+	mapped
+	broadcast BroadcastRound
+}
+
+// StoreBroadcastMessage implements BroadcastRound.
+func (m *mappedBroadcast) StoreBroadcastMessage(msg Message) error {
+	return m.broadcast.StoreBroadcastMessage(msg)
+}
+
+// BroadcastContent implements BroadcastRound.
+func (m *mappedBroadcast) BroadcastContent() BroadcastContent {
+	return m.broadcast.BroadcastContent()
+}
@@ -0,0 +1,187 @@
+// Package address derives Bitcoin and Ethereum addresses from threshold public keys,
+// such as cmp.Config.PublicPoint(), frost.Config.PublicKey or frost.TaprootConfig.PublicKey.
+//
+// Bitcoin addresses depend on the network, which is given by its chaincfg.Params,
+// e.g. &chaincfg.MainNetParams or &chaincfg.TestNet3Params.
+// Ethereum addresses are the same on every network.
+package address
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/taproot"
+	"golang.org/x/crypto/sha3"
+)
+
+// Kind is a type of Bitcoin address.
+type Kind int
+
+const (
+	// P2PKH pays to the hash of a compressed public key, with a base58 address.
+	P2PKH Kind = iota
+	// P2WPKH pays to the hash of a compressed public key in a segwit v0 output, with a bech32 address.
+	P2WPKH
+	// P2TR pays to the BIP-86 output key of a taproot internal key, with a bech32m address.
+	P2TR
+)
+
+// Kinds lists every Kind.
+var Kinds = []Kind{P2PKH, P2WPKH, P2TR}
+
+func (k Kind) String() string {
+	switch k {
+	case P2PKH:
+		return "p2pkh"
+	case P2WPKH:
+		return "p2wpkh"
+	case P2TR:
+		return "p2tr"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+var errNotSecp256k1 = errors.New("address: public key is not a secp256k1 point")
+
+// Compressed returns the 33 byte SEC1 encoding of public.
+func Compressed(public curve.Point) ([]byte, error) {
+	p, ok := public.(*curve.Secp256k1Point)
+	if !ok {
+		return nil, errNotSecp256k1
+	}
+	if p.IsIdentity() {
+		return nil, errors.New("address: public key is the identity")
+	}
+	return p.MarshalBinary()
+}
+
+// Uncompressed returns the 65 byte SEC1 encoding of public, starting with 0x04.
+func Uncompressed(public curve.Point) ([]byte, error) {
+	data, err := Compressed(public)
+	if err != nil {
+		return nil, err
+	}
+	key, err := secp256k1.ParsePubKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("address: %w", err)
+	}
+	return key.SerializeUncompressed(), nil
+}
+
+// TaprootKey returns the x-only key of public, as used by BIP-340.
+//
+// A point and its negation have the same x-only key.
+func TaprootKey(public curve.Point) (taproot.PublicKey, error) {
+	p, ok := public.(*curve.Secp256k1Point)
+	if !ok {
+		return nil, errNotSecp256k1
+	}
+	if p.IsIdentity() {
+		return nil, errors.New("address: public key is the identity")
+	}
+	return p.XBytes(), nil
+}
+
+// OutputKey returns the BIP-86 taproot output key of public, which is the internal key,
+// tweaked to commit to an empty script tree.
+//
+// See: https://github.com/bitcoin/bips/blob/master/bip-0086.mediawiki#address-derivation
+func OutputKey(public curve.Point) (taproot.PublicKey, error) {
+	internal, err := TaprootKey(public)
+	if err != nil {
+		return nil, err
+	}
+	_, output, err := internal.Tweak(nil)
+	if err != nil {
+		return nil, fmt.Errorf("address: %w", err)
+	}
+	return output, nil
+}
+
+// Ethereum returns the Ethereum address of public: the last 20 bytes of the Keccak-256 hash
+// of its uncompressed encoding, without the 0x04 prefix.
+//
+// The Hex method of the result gives the EIP-55 checksummed form.
+func Ethereum(public curve.Point) (common.Address, error) {
+	data, err := Uncompressed(public)
+	if err != nil {
+		return common.Address{}, err
+	}
+	h := sha3.NewLegacyKeccak256()
+	_, _ = h.Write(data[1:])
+	return common.BytesToAddress(h.Sum(nil)[12:]), nil
+}
+
+// Bitcoin returns the address of the given kind for public on net.
+//
+// For P2TR, the x-only key of public is the internal key, and the address pays to its BIP-86 output key,
+// committing to an empty script tree, like standard wallets. Spending it with frost requires signing with
+// the tweaked config returned by frost.TaprootConfig.Tweak(nil).
+func Bitcoin(kind Kind, public curve.Point, net *chaincfg.Params) (btcutil.Address, error) {
+	var (
+		addr btcutil.Address
+		err  error
+	)
+	switch kind {
+	case P2PKH:
+		addr, err = PayToPubKeyHash(public, net)
+	case P2WPKH:
+		addr, err = PayToWitnessPubKeyHash(public, net)
+	case P2TR:
+		var key taproot.PublicKey
+		if key, err = OutputKey(public); err == nil {
+			addr, err = PayToTaproot(key, net)
+		}
+	default:
+		err = fmt.Errorf("address: unknown kind %v", kind)
+	}
+	if err != nil {
+		// don't return a typed nil pointer
+		return nil, err
+	}
+	return addr, nil
+}
+
+// PayToPubKeyHash returns the P2PKH address of public on net.
+func PayToPubKeyHash(public curve.Point, net *chaincfg.Params) (*btcutil.AddressPubKeyHash, error) {
+	data, err := Compressed(public)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(data), net)
+	if err != nil {
+		return nil, fmt.Errorf("address: %w", err)
+	}
+	return addr, nil
+}
+
+// PayToWitnessPubKeyHash returns the P2WPKH address of public on net.
+func PayToWitnessPubKeyHash(public curve.Point, net *chaincfg.Params) (*btcutil.AddressWitnessPubKeyHash, error) {
+	data, err := Compressed(public)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(data), net)
+	if err != nil {
+		return nil, fmt.Errorf("address: %w", err)
+	}
+	return addr, nil
+}
+
+// PayToTaproot returns the P2TR address of the output key public on net.
+func PayToTaproot(public taproot.PublicKey, net *chaincfg.Params) (*btcutil.AddressTaproot, error) {
+	if len(public) != 32 {
+		return nil, fmt.Errorf("address: taproot key has %d bytes, expected 32", len(public))
+	}
+	addr, err := btcutil.NewAddressTaproot(public, net)
+	if err != nil {
+		return nil, fmt.Errorf("address: %w", err)
+	}
+	return addr, nil
+}
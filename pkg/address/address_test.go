@@ -0,0 +1,92 @@
+package address
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/taproot"
+)
+
+func publicKey(t *testing.T, secret string) curve.Point {
+	data, err := hex.DecodeString(secret)
+	require.NoError(t, err)
+	s := curve.Secp256k1{}.NewScalar()
+	require.NoError(t, s.UnmarshalBinary(data))
+	return s.ActOnBase()
+}
+
+func TestEthereum(t *testing.T) {
+	vectors := []struct {
+		secret, address string
+	}{
+		{"0000000000000000000000000000000000000000000000000000000000000001", "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf"},
+		{"4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318", "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"},
+	}
+	for _, v := range vectors {
+		addr, err := Ethereum(publicKey(t, v.secret))
+		require.NoError(t, err)
+		assert.Equal(t, v.address, addr.Hex())
+	}
+}
+
+func TestBitcoin(t *testing.T) {
+	// the public key of the secret key 1 is the generator
+	G := publicKey(t, "0000000000000000000000000000000000000000000000000000000000000001")
+	vectors := []struct {
+		kind    Kind
+		net     *chaincfg.Params
+		address string
+	}{
+		{P2PKH, &chaincfg.MainNetParams, "1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH"},
+		{P2PKH, &chaincfg.TestNet3Params, "mrCDrCybB6J1vRfbwM5hemdJz73FwDBC8r"},
+		// BIP-173
+		{P2WPKH, &chaincfg.MainNetParams, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+		{P2WPKH, &chaincfg.TestNet3Params, "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx"},
+	}
+	for _, v := range vectors {
+		addr, err := Bitcoin(v.kind, G, v.net)
+		require.NoError(t, err)
+		assert.Equal(t, v.address, addr.EncodeAddress(), "%v on %s", v.kind, v.net.Name)
+		assert.True(t, addr.IsForNet(v.net))
+	}
+}
+
+func TestTaproot(t *testing.T) {
+	// BIP-86, first receiving address of the test mnemonic
+	outputKey, err := hex.DecodeString("a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c")
+	require.NoError(t, err)
+
+	addr, err := PayToTaproot(taproot.PublicKey(outputKey), &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	assert.Equal(t, "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr", addr.EncodeAddress())
+
+	addr, err = PayToTaproot(taproot.PublicKey(outputKey), &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(addr.EncodeAddress(), "tb1p"))
+	assert.Equal(t, outputKey, addr.ScriptAddress())
+
+	// the internal key of the same vector is tweaked into its output key
+	internalKey, err := hex.DecodeString("cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115")
+	require.NoError(t, err)
+	P, err := curve.Secp256k1{}.LiftX(internalKey)
+	require.NoError(t, err)
+	key, err := OutputKey(P)
+	require.NoError(t, err)
+	assert.Equal(t, outputKey, []byte(key))
+
+	// a point and its negation share their taproot address
+	a, err := Bitcoin(P2TR, P, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	b, err := Bitcoin(P2TR, P.Negate(), &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	assert.Equal(t, "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr", a.EncodeAddress())
+	assert.Equal(t, a.EncodeAddress(), b.EncodeAddress())
+
+	_, err = PayToTaproot(taproot.PublicKey(outputKey[1:]), &chaincfg.MainNetParams)
+	assert.Error(t, err)
+}
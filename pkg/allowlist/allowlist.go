@@ -0,0 +1,182 @@
+// Package allowlist implements filters of the parties allowed to sign with a threshold key,
+// shared by the cmp and frost protocols.
+package allowlist
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/taurusgroup/multi-party-sig/filters"
+	// register the filters allow lists can be decoded into.
+	// Immutable filters like xor are useless as allow lists, since nobody can be suspended.
+	_ "github.com/taurusgroup/multi-party-sig/filters/bloom"
+	_ "github.com/taurusgroup/multi-party-sig/filters/cuckoo"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+)
+
+// ErrNoFilter is returned when an allow list is used without a filter.
+var ErrNoFilter = errors.New("allowlist: allow list has no filter")
+
+// AllowList is a filter of the parties allowed to sign with a threshold key.
+//
+// It is a cheap policy layer on top of the threshold: a party can be suspended by deleting it
+// from the filter, without running a refresh. Since filters have false positives, a suspended party
+// is still allowed with the false positive rate of the filter, which should be chosen accordingly.
+//
+// Every signer must use the same allow list, since it is part of the session identifier
+// of the signing protocols.
+type AllowList struct {
+	// Name is the name the filter is registered as, see filters.Names.
+	Name string
+	// Filter holds the IDs of the parties allowed to sign.
+	Filter filters.Filter
+	// E is the false positive rate the filter was built with.
+	// It is used when the filter needs to be rebuilt, see Update.
+	E float64
+}
+
+// DefaultAllowListName and DefaultAllowListRate describe the filter used by Update
+// when there is no allow list yet.
+const (
+	DefaultAllowListName = "cuckoo"
+	DefaultAllowListRate = 1.0 / 65536
+)
+
+// NewAllowList builds an allow list of ids, using the filter registered as name
+// with false positive rate e.
+//
+// Filters supporting deletion, like "cuckoo" or "counting-bloom", allow parties to be suspended.
+func NewAllowList(name string, ids []party.ID, e float64) (*AllowList, error) {
+	items := make([]string, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, string(id))
+	}
+	f, err := filters.Build(name, items, e)
+	if err != nil {
+		return nil, fmt.Errorf("allowlist: %w", err)
+	}
+	return &AllowList{Name: name, Filter: f, E: e}, nil
+}
+
+// Update returns a new allow list holding exactly members, which must be a subset of parties.
+// The receiver is not modified, and may be nil, in which case the new list is built with
+// DefaultAllowListName and DefaultAllowListRate.
+//
+// The result only depends on the encoding of a, parties and members, so that every party
+// updating the same allow list obtains the same filter:
+//   - if the filter supports deletion, the parties which are not members are deleted from a copy of it,
+//     and new members are added, both in sorted order, with the filter seeded by the set of members;
+//   - otherwise, or if a member got lost along the way, the filter is rebuilt from members with filters.Build.
+func (a *AllowList) Update(parties, members []party.ID) (*AllowList, error) {
+	known := make(map[party.ID]bool, len(parties))
+	for _, id := range parties {
+		known[id] = true
+	}
+	isMember := make(map[party.ID]bool, len(members))
+	for _, id := range members {
+		if !known[id] {
+			return nil, fmt.Errorf("allowlist: member %s is not a party", id)
+		}
+		isMember[id] = true
+	}
+
+	if a == nil {
+		return NewAllowList(DefaultAllowListName, members, DefaultAllowListRate)
+	}
+	e := a.E
+	if e <= 0 {
+		e = DefaultAllowListRate
+	}
+	if updated, ok := a.update(party.NewIDSlice(parties), party.NewIDSlice(members), isMember); ok {
+		updated.E = e
+		return updated, nil
+	}
+	return NewAllowList(a.Name, members, e)
+}
+
+// update tries to update a copy of the allow list in place, and reports whether it succeeded.
+func (a *AllowList) update(parties, members party.IDSlice, isMember map[party.ID]bool) (*AllowList, bool) {
+	if a.Filter == nil {
+		return nil, false
+	}
+	updated, err := a.Copy()
+	if err != nil {
+		return nil, false
+	}
+	f := updated.Filter
+	d, ok := f.(filters.Deleter)
+	if !ok {
+		return nil, false
+	}
+	items := make([]string, 0, len(members))
+	for _, id := range members {
+		items = append(items, string(id))
+	}
+	if s, ok := f.(filters.Seeder); ok {
+		s.Seed(filters.SetDigest(items))
+	}
+
+	for _, id := range parties {
+		if !isMember[id] && updated.Allows(id) {
+			d.Delete(string(id))
+		}
+	}
+	for _, id := range members {
+		if !updated.Allows(id) {
+			if err := f.Add(string(id)); err != nil {
+				return nil, false
+			}
+		}
+	}
+	// deleting a false positive may have removed a member sharing its fingerprint
+	for _, id := range members {
+		if !updated.Allows(id) {
+			return nil, false
+		}
+	}
+	return updated, true
+}
+
+// Copy returns a deep copy of the allow list, so that suspending a party in one of them
+// doesn't affect the other.
+func (a *AllowList) Copy() (*AllowList, error) {
+	if a.Filter == nil {
+		return nil, ErrNoFilter
+	}
+	data, err := a.Filter.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("allowlist: %w", err)
+	}
+	f, err := filters.Decode(a.Name, data)
+	if err != nil {
+		return nil, fmt.Errorf("allowlist: %w", err)
+	}
+	return &AllowList{Name: a.Name, Filter: f, E: a.E}, nil
+}
+
+// Allows reports whether id may sign.
+func (a *AllowList) Allows(id party.ID) bool {
+	return a.Filter.Test(string(id))
+}
+
+// Suspend removes id from the allow list.
+//
+// It fails if the filter doesn't support deletion, or if id isn't in it.
+func (a *AllowList) Suspend(id party.ID) error {
+	d, ok := a.Filter.(filters.Deleter)
+	if !ok {
+		return fmt.Errorf("allowlist: %s filters don't support deletion", a.Name)
+	}
+	if !d.Delete(string(id)) {
+		return fmt.Errorf("allowlist: party %s not found", id)
+	}
+	return nil
+}
+
+// Reinstate adds id back to the allow list.
+func (a *AllowList) Reinstate(id party.ID) error {
+	if a.Allows(id) {
+		return nil
+	}
+	return a.Filter.Add(string(id))
+}
@@ -0,0 +1,40 @@
+package allowlist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+)
+
+func TestAllowList(t *testing.T) {
+	partyIDs := []party.ID{"a", "b", "c", "d", "e"}
+	a, err := NewAllowList("cuckoo", partyIDs, 0.0001)
+	require.NoError(t, err)
+	for _, id := range partyIDs {
+		assert.True(t, a.Allows(id))
+	}
+
+	c, err := a.Copy()
+	require.NoError(t, err)
+	require.NoError(t, c.Suspend(partyIDs[0]))
+	assert.False(t, c.Allows(partyIDs[0]), "suspended party should not be allowed")
+	assert.True(t, a.Allows(partyIDs[0]), "copy should not share the filter")
+	require.NoError(t, c.Reinstate(partyIDs[0]))
+	assert.True(t, c.Allows(partyIDs[0]))
+
+	updated, err := a.Update(partyIDs, partyIDs[1:])
+	require.NoError(t, err)
+	assert.False(t, updated.Allows(partyIDs[0]), "removed member should not be allowed")
+	assert.True(t, a.Allows(partyIDs[0]), "update should not modify the receiver")
+	_, err = a.Update(partyIDs[1:], partyIDs)
+	assert.Error(t, err, "members must be parties")
+
+	updated, err = (*AllowList)(nil).Update(partyIDs, partyIDs[:3])
+	require.NoError(t, err)
+	assert.Equal(t, DefaultAllowListName, updated.Name)
+
+	_, err = (&AllowList{Name: "cuckoo"}).Copy()
+	assert.ErrorIs(t, err, ErrNoFilter)
+}
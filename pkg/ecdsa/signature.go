@@ -1,6 +1,12 @@
 package ecdsa
 
 import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
 )
 
@@ -32,41 +38,210 @@ func (sig Signature) Verify(X curve.Point, hash []byte) bool {
 	return R2.Equal(sig.R)
 }
 
-// get a signature in ethereum format
-func (sig Signature) SigEthereum() ([]byte, error) {
-	IsOverHalfOrder := sig.S.IsOverHalfOrder() // s-values greater than secp256k1n/2 are considered invalid
+// Normalize returns the low-S form of the signature, where S is at most half the group order.
+//
+// If S is negated, then so is R, so that the result still verifies. sig is not modified.
+func (sig Signature) Normalize() Signature {
+	group := sig.S.Curve()
+	if !sig.S.IsOverHalfOrder() {
+		return Signature{R: sig.R.Add(group.NewPoint()), S: group.NewScalar().Set(sig.S)}
+	}
+	return Signature{R: sig.R.Negate(), S: group.NewScalar().Set(sig.S).Negate()}
+}
 
-	if IsOverHalfOrder {
-		sig.S.Negate()
+// RecoverPublicKey returns the public key X for which the signature verifies with hash,
+// that is X = r⁻¹⋅(s⋅R - m⋅G).
+//
+// This only needs the full point R, which is known for signatures produced by the protocols,
+// or parsed with ParseRecoverable.
+func (sig Signature) RecoverPublicKey(hash []byte) (curve.Point, error) {
+	if sig.R == nil || sig.S == nil {
+		return nil, errors.New("ecdsa: nil signature")
 	}
+	group := sig.S.Curve()
+	r := sig.R.XScalar()
+	if r.IsZero() || sig.S.IsZero() {
+		return nil, errors.New("ecdsa: zero signature")
+	}
+	m := curve.FromHash(group, hash)
+	sR := sig.S.Act(sig.R)
+	X := group.NewScalar().Set(r).Invert().Act(sR.Sub(m.ActOnBase()))
+	if X.IsIdentity() {
+		return nil, errors.New("ecdsa: recovered public key is the identity")
+	}
+	return X, nil
+}
 
-	r, err := sig.R.MarshalBinary()
+// DER returns the DER encoding of the normalized signature, as used in Bitcoin scripts
+// (without the sighash type byte).
+func (sig Signature) DER() ([]byte, error) {
+	r, s, _, err := sig.Normalize().encode()
 	if err != nil {
 		return nil, err
 	}
-	s, err := sig.S.MarshalBinary()
+	return asn1.Marshal(derSignature{
+		R: new(big.Int).SetBytes(r),
+		S: new(big.Int).SetBytes(s),
+	})
+}
+
+// Compact returns r || s for the normalized signature, with both values on 32 bytes.
+func (sig Signature) Compact() ([]byte, error) {
+	r, s, _, err := sig.Normalize().encode()
 	if err != nil {
 		return nil, err
 	}
+	out := make([]byte, 0, 64)
+	out = append(out, r...)
+	return append(out, s...), nil
+}
 
-	rs := make([]byte, 0, 65)
-	rs = append(rs, r...)
-	rs = append(rs, s...)
+// Recoverable returns r || s || v for the normalized signature, where v is the recovery id:
+// its first bit is the parity of the y coordinate of R, and its second bit is set if the
+// x coordinate of R is at least the group order.
+func (sig Signature) Recoverable() ([]byte, error) {
+	r, s, v, err := sig.Normalize().encode()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, 65)
+	out = append(out, r...)
+	out = append(out, s...)
+	return append(out, v), nil
+}
 
-	if IsOverHalfOrder {
-		v := rs[0] - 2 // Convert to Ethereum signature format with 'recovery id' v at the end.
-		copy(rs, rs[1:])
-		rs[64] = v ^ 1
-	} else {
-		v := rs[0] - 2
-		copy(rs, rs[1:])
-		rs[64] = v
+// SigEthereum returns the signature in the format expected by go-ethereum, r || s || v,
+// with a low s and v in {0, 1}. It is the same as Recoverable, and sig is not modified.
+func (sig Signature) SigEthereum() ([]byte, error) {
+	return sig.Recoverable()
+}
+
+// encode returns the 32 byte values r and s, along with the recovery id of the signature.
+func (sig Signature) encode() (r, s []byte, v byte, err error) {
+	R, ok := sig.R.(*curve.Secp256k1Point)
+	if !ok {
+		return nil, nil, 0, errors.New("ecdsa: signature is not over secp256k1")
+	}
+	if R.IsIdentity() || sig.S.IsZero() {
+		return nil, nil, 0, errors.New("ecdsa: zero signature")
+	}
+	if r, err = R.XScalar().MarshalBinary(); err != nil {
+		return nil, nil, 0, err
 	}
+	if s, err = sig.S.MarshalBinary(); err != nil {
+		return nil, nil, 0, err
+	}
+	if !R.HasEvenY() {
+		v |= 1
+	}
+	if !bytes.Equal(r, R.XBytes()) {
+		v |= 2
+	}
+	return r, s, v, nil
+}
 
-	r[0] = rs[64] + 2
-	if err := sig.R.UnmarshalBinary(r); err != nil {
+type derSignature struct {
+	R, S *big.Int
+}
+
+// ParseDER parses a DER encoded signature over secp256k1.
+//
+// The encoding only contains the x coordinate of R, so its y coordinate is chosen such that
+// the signature verifies for public and hash, and an error is returned if neither does.
+func ParseDER(data []byte, public curve.Point, hash []byte) (*Signature, error) {
+	var der derSignature
+	rest, err := asn1.Unmarshal(data, &der)
+	if err != nil {
+		return nil, fmt.Errorf("ecdsa: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("ecdsa: trailing data after DER signature")
+	}
+	// asn1 accepts some BER encodings, which re-encoding catches
+	if canonical, err := asn1.Marshal(der); err != nil || !bytes.Equal(canonical, data) {
+		return nil, errors.New("ecdsa: signature is not canonically encoded")
+	}
+	if der.R.Sign() <= 0 || der.S.Sign() <= 0 || der.R.BitLen() > 256 || der.S.BitLen() > 256 {
+		return nil, errors.New("ecdsa: signature values out of range")
+	}
+	return parseVerified(der.R.FillBytes(make([]byte, 32)), der.S.FillBytes(make([]byte, 32)), public, hash)
+}
+
+// ParseCompact parses r || s, as returned by Compact.
+//
+// Like ParseDER, it needs public and hash to determine R.
+func ParseCompact(data []byte, public curve.Point, hash []byte) (*Signature, error) {
+	if len(data) != 64 {
+		return nil, fmt.Errorf("ecdsa: compact signature has %d bytes, expected 64", len(data))
+	}
+	return parseVerified(data[:32], data[32:], public, hash)
+}
+
+// ParseRecoverable parses r || s || v, as returned by Recoverable.
+// The public key can then be obtained with RecoverPublicKey.
+func ParseRecoverable(data []byte) (*Signature, error) {
+	if len(data) != 65 {
+		return nil, fmt.Errorf("ecdsa: recoverable signature has %d bytes, expected 65", len(data))
+	}
+	v := data[64]
+	if v > 3 {
+		return nil, fmt.Errorf("ecdsa: invalid recovery id %d", v)
+	}
+	x := data[:32]
+	if v&2 != 0 {
+		order := curve.Secp256k1{}.Order().Big()
+		xBig := new(big.Int).Add(new(big.Int).SetBytes(x), order)
+		if xBig.BitLen() > 256 {
+			return nil, errors.New("ecdsa: x coordinate of R out of range")
+		}
+		x = xBig.FillBytes(make([]byte, 32))
+	}
+	sig, err := parse(x, data[32:64])
+	if err != nil {
+		return nil, err
+	}
+	if v&1 != 0 {
+		sig.R = sig.R.Negate()
+	}
+	if sig.R.XScalar().IsZero() {
+		return nil, errors.New("ecdsa: zero signature")
+	}
+	return sig, nil
+}
+
+// parseVerified parses the signature with the x coordinate of R given by r,
+// and returns the choice of R for which it verifies.
+func parseVerified(r, s []byte, public curve.Point, hash []byte) (*Signature, error) {
+	if err := (curve.Secp256k1{}).NewScalar().UnmarshalBinary(r); err != nil {
+		return nil, errors.New("ecdsa: r is not less than the group order")
+	}
+	sig, err := parse(r, s)
+	if err != nil {
 		return nil, err
 	}
+	if sig.Verify(public, hash) {
+		return sig, nil
+	}
+	sig.R = sig.R.Negate()
+	if sig.Verify(public, hash) {
+		return sig, nil
+	}
+	return nil, errors.New("ecdsa: signature does not verify")
+}
 
-	return rs, nil
+// parse returns the signature with R the point with x coordinate x and an even y coordinate.
+func parse(x, s []byte) (*Signature, error) {
+	group := curve.Secp256k1{}
+	R, err := group.LiftX(x)
+	if err != nil {
+		return nil, fmt.Errorf("ecdsa: %w", err)
+	}
+	S := group.NewScalar()
+	if err = S.UnmarshalBinary(s); err != nil {
+		return nil, fmt.Errorf("ecdsa: %w", err)
+	}
+	if S.IsZero() {
+		return nil, errors.New("ecdsa: zero signature")
+	}
+	return &Signature{R: R, S: S}, nil
 }
@@ -1,9 +1,12 @@
 package ecdsa
 
 import (
+	"bytes"
 	"crypto/rand"
 	"testing"
 
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	decred "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/sample"
 )
@@ -59,3 +62,143 @@ func TestSignature_Verify_Zero(t *testing.T) {
 		t.Error("zero R/S signature should not verify")
 	}
 }
+
+func TestSignature_Encodings(t *testing.T) {
+	group := curve.Secp256k1{}
+
+	m := make([]byte, 32)
+	_, _ = rand.Read(m)
+	x := sample.Scalar(rand.Reader, group)
+	X := x.ActOnBase()
+	data, err := X.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := secp256k1.ParsePubKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 8; i++ {
+		sig := NewSignature(x, m, nil)
+		// (-R, -s) verifies too, which covers both high and low s
+		if i%2 == 1 {
+			sig = &Signature{R: sig.R.Negate(), S: group.NewScalar().Set(sig.S).Negate()}
+		}
+		if !sig.Verify(X, m) {
+			t.Fatal("verify failed")
+		}
+		R, S := sig.R.Add(group.NewPoint()), group.NewScalar().Set(sig.S)
+
+		normalized := sig.Normalize()
+		if normalized.S.IsOverHalfOrder() || !normalized.Verify(X, m) {
+			t.Error("normalized signature should have a low s and verify")
+		}
+
+		der, err := sig.DER()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsedDER, err := decred.ParseDERSignature(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !parsedDER.Verify(m, key) {
+			t.Error("DER signature should verify")
+		}
+
+		compact, err := sig.Compact()
+		if err != nil {
+			t.Fatal(err)
+		}
+		recoverable, err := sig.Recoverable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(compact) != 64 || len(recoverable) != 65 || !bytes.Equal(compact, recoverable[:64]) {
+			t.Error("recoverable signature should extend the compact one")
+		}
+		ethereum, err := sig.SigEthereum()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ethereum, recoverable) {
+			t.Error("ethereum signature should be the recoverable one")
+		}
+		// decred's compact format is v || r || s, with v = 27 + recovery id + 4 for a compressed key
+		recovered, compressed, err := decred.RecoverCompact(append([]byte{27 + 4 + recoverable[64]}, compact...), m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !compressed || !recovered.IsEqual(key) {
+			t.Error("recovery id should recover the public key")
+		}
+
+		if !sig.R.Equal(R) || !sig.S.Equal(S) {
+			t.Fatal("encodings should not modify the signature")
+		}
+
+		for name, parse := range map[string]func() (*Signature, error){
+			"der":         func() (*Signature, error) { return ParseDER(der, X, m) },
+			"compact":     func() (*Signature, error) { return ParseCompact(compact, X, m) },
+			"recoverable": func() (*Signature, error) { return ParseRecoverable(recoverable) },
+		} {
+			parsed, err := parse()
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if !parsed.R.Equal(normalized.R) || !parsed.S.Equal(normalized.S) {
+				t.Error(name, "parsed signature should be the normalized one")
+			}
+			public, err := parsed.RecoverPublicKey(m)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if !public.Equal(X) {
+				t.Error(name, "wrong public key recovered")
+			}
+		}
+
+		public, err := sig.RecoverPublicKey(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !public.Equal(X) {
+			t.Error("wrong public key recovered")
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	group := curve.Secp256k1{}
+
+	m := []byte("hello")
+	x := sample.Scalar(rand.Reader, group)
+	X := x.ActOnBase()
+	sig := NewSignature(x, m, nil)
+	der, err := sig.DER()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoverable, err := sig.Recoverable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = ParseDER(der, X, []byte("other")); err == nil {
+		t.Error("signature for another message should not parse")
+	}
+	if _, err = ParseDER(append(der, 0), X, m); err == nil {
+		t.Error("trailing data should not parse")
+	}
+	if _, err = ParseCompact(recoverable, X, m); err == nil {
+		t.Error("wrong length should not parse")
+	}
+	recoverable[64] = 4
+	if _, err = ParseRecoverable(recoverable); err == nil {
+		t.Error("invalid recovery id should not parse")
+	}
+	if _, err = ParseRecoverable(make([]byte, 65)); err == nil {
+		t.Error("zero signature should not parse")
+	}
+}
@@ -19,6 +19,26 @@ import (
 // An optional sessionID can be provided, which should unique among all protocol executions.
 type StartFunc func(sessionID []byte) (round.Session, error)
 
+// MapResult returns a StartFunc running the same protocol as start,
+// whose result is f applied to the result of start.
+func MapResult(start StartFunc, f func(result interface{}) interface{}) StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		r, err := start(sessionID)
+		if err != nil {
+			return nil, err
+		}
+		return round.MapResult(r, f), nil
+	}
+}
+
+// Failed returns a StartFunc which fails with err, for constructors which must return a StartFunc
+// but detect an error before the protocol starts.
+func Failed(err error) StartFunc {
+	return func([]byte) (round.Session, error) {
+		return nil, err
+	}
+}
+
 // Handler represents some kind of handler for a protocol.
 type Handler interface {
 	// Result should return the result of running the protocol, or an error
@@ -0,0 +1,37 @@
+package taproot
+
+import (
+	"errors"
+
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+)
+
+// Tweak returns the BIP-341 tweak t = hash_TapTweak(pk || merkleRoot) of the internal key pk,
+// along with the output key Q = lift_x(pk) + t * G.
+//
+// merkleRoot is empty for an output without a script path, as in BIP-86, and is the 32 byte root
+// of the script tree otherwise. A signature for Q can be produced by adding t to the secret key
+// of lift_x(pk), and negating the result if Q has an odd y coordinate.
+//
+// See: https://github.com/bitcoin/bips/blob/master/bip-0341.mediawiki#constructing-and-spending-taproot-outputs
+func (pk PublicKey) Tweak(merkleRoot []byte) (*curve.Secp256k1Scalar, PublicKey, error) {
+	if len(pk) != 32 {
+		return nil, nil, errors.New("taproot: public key must be 32 bytes")
+	}
+	if len(merkleRoot) != 0 && len(merkleRoot) != 32 {
+		return nil, nil, errors.New("taproot: merkle root must be empty or 32 bytes")
+	}
+	P, err := curve.Secp256k1{}.LiftX(pk)
+	if err != nil {
+		return nil, nil, err
+	}
+	t := new(curve.Secp256k1Scalar)
+	if err := t.UnmarshalBinary(TaggedHash("TapTweak", pk, merkleRoot)); err != nil {
+		return nil, nil, errors.New("taproot: tweak overflows the group order")
+	}
+	Q := P.Add(t.ActOnBase()).(*curve.Secp256k1Point)
+	if Q.IsIdentity() {
+		return nil, nil, errors.New("taproot: output key is the identity")
+	}
+	return t, Q.XBytes(), nil
+}
@@ -0,0 +1,240 @@
+// Package watch implements a watch-only wallet over the BIP-32 children of a threshold public key.
+//
+// It derives the public keys of a gap-limit window of children from public data only,
+// i.e. the shared public key and chain key of a config, computes their addresses,
+// and keeps them in a cuckoo filter, which can answer whether an output pays to the wallet,
+// and be handed to a light client for the same purpose.
+//
+// The children match those of cmp.Config.DeriveBIP32 and frost.TaprootConfig.DeriveChild.
+package watch
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/taurusgroup/multi-party-sig/filters/cuckoo"
+	"github.com/taurusgroup/multi-party-sig/internal/bip32"
+	"github.com/taurusgroup/multi-party-sig/pkg/address"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/taproot"
+)
+
+// DefaultGapLimit is the number of unused addresses watched past the last used one, as in BIP-44.
+const DefaultGapLimit = 20
+
+// FalsePositiveRate is the false positive rate of the filter of a Wallet.
+const FalsePositiveRate = 1.0 / (1 << 20)
+
+// AddressFunc computes the address of a child public key.
+type AddressFunc func(public curve.Point) (string, error)
+
+// Ethereum returns the checksummed Ethereum address of public.
+func Ethereum(public curve.Point) (string, error) {
+	addr, err := address.Ethereum(public)
+	if err != nil {
+		return "", err
+	}
+	return addr.Hex(), nil
+}
+
+// Bitcoin returns an AddressFunc computing Bitcoin addresses of the given kind on net.
+func Bitcoin(kind address.Kind, net *chaincfg.Params) AddressFunc {
+	return func(public curve.Point) (string, error) {
+		addr, err := address.Bitcoin(kind, public, net)
+		if err != nil {
+			return "", err
+		}
+		return addr.EncodeAddress(), nil
+	}
+}
+
+// Wallet watches the addresses of the children of a public key, with indices in [0, Next()+gapLimit).
+type Wallet struct {
+	public   *curve.Secp256k1Point
+	chainKey []byte
+	taproot  bool
+	addr     AddressFunc
+	gapLimit uint32
+
+	// next is one more than the last used index
+	next uint32
+	// end is one more than the last derived index
+	end     uint32
+	indices map[string]uint32
+	filter  *cuckoo.Cuckoo
+}
+
+// New returns a wallet watching the children of the ECDSA public key public, with chain key chainKey,
+// such as the PublicPoint() and ChainKey of a cmp.Config.
+//
+// gapLimit is the number of unused children watched past the last used one, see DefaultGapLimit.
+func New(public curve.Point, chainKey []byte, gapLimit uint32, addr AddressFunc) (*Wallet, error) {
+	p, ok := public.(*curve.Secp256k1Point)
+	if !ok {
+		return nil, errors.New("watch: public key is not a secp256k1 point")
+	}
+	return newWallet(p, chainKey, false, gapLimit, addr)
+}
+
+// NewTaproot returns a wallet watching the children of a taproot public key, with chain key chainKey,
+// such as the PublicKey and ChainKey of a frost.TaprootConfig.
+func NewTaproot(public taproot.PublicKey, chainKey []byte, gapLimit uint32, addr AddressFunc) (*Wallet, error) {
+	p, err := curve.Secp256k1{}.LiftX(public)
+	if err != nil {
+		return nil, fmt.Errorf("watch: %w", err)
+	}
+	return newWallet(p, chainKey, true, gapLimit, addr)
+}
+
+func newWallet(public *curve.Secp256k1Point, chainKey []byte, taproot bool, gapLimit uint32, addr AddressFunc) (*Wallet, error) {
+	if len(chainKey) == 0 {
+		return nil, errors.New("watch: empty chain key")
+	}
+	if gapLimit == 0 {
+		return nil, errors.New("watch: gap limit must be positive")
+	}
+	w := &Wallet{
+		public:   public,
+		chainKey: append([]byte{}, chainKey...),
+		taproot:  taproot,
+		addr:     addr,
+		gapLimit: gapLimit,
+		indices:  make(map[string]uint32),
+	}
+	if err := w.extend(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Child returns the public key of the child at index i, and false if i is not a valid index,
+// in which case BIP-32 says to skip to the next one.
+//
+// For taproot wallets, the point returned has an even y coordinate, like the key of the
+// corresponding frost.TaprootConfig.
+func (w *Wallet) Child(i uint32) (curve.Point, bool, error) {
+	if i>>31 != 0 {
+		return nil, false, fmt.Errorf("watch: index %d is hardened", i)
+	}
+	scalar, _, err := bip32.DeriveScalar(w.public, w.chainKey, i)
+	if errors.Is(err, bip32.ErrInvalidIndex) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("watch: %w", err)
+	}
+	child := w.public.Add(scalar.ActOnBase()).(*curve.Secp256k1Point)
+	if child.IsIdentity() {
+		return nil, false, nil
+	}
+	if w.taproot && !child.HasEvenY() {
+		child = child.Negate().(*curve.Secp256k1Point)
+	}
+	return child, true, nil
+}
+
+// Next returns the first index which hasn't been used yet.
+func (w *Wallet) Next() uint32 {
+	return w.next
+}
+
+// Address returns the address of the child at index i.
+func (w *Wallet) Address(i uint32) (string, error) {
+	child, ok, err := w.Child(i)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("watch: invalid index %d", i)
+	}
+	return w.addr(child)
+}
+
+// Match reports whether addr belongs to one of the watched children, and if so, its index.
+//
+// The filter is tested first, which only costs a few hash computations for foreign addresses.
+func (w *Wallet) Match(addr string) (uint32, bool) {
+	if !w.filter.Test(addr) {
+		return 0, false
+	}
+	i, ok := w.indices[addr]
+	return i, ok
+}
+
+// MatchScript is like Match, for a Bitcoin output script on net.
+func (w *Wallet) MatchScript(pkScript []byte, net *chaincfg.Params) (uint32, bool) {
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, net)
+	if err != nil {
+		return 0, false
+	}
+	for _, addr := range addrs {
+		if i, ok := w.Match(addr.EncodeAddress()); ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Use marks index i as used, which slides the window so that gapLimit unused children
+// past it are watched.
+func (w *Wallet) Use(i uint32) error {
+	if i>>31 != 0 {
+		return fmt.Errorf("watch: index %d is hardened", i)
+	}
+	if i < w.next {
+		return nil
+	}
+	w.next = i + 1
+	return w.extend()
+}
+
+// Filter returns the filter holding the addresses of the watched children.
+// It must not be modified.
+func (w *Wallet) Filter() *cuckoo.Cuckoo {
+	return w.filter
+}
+
+// Len returns the number of watched addresses.
+func (w *Wallet) Len() int {
+	return len(w.indices)
+}
+
+// extend derives the children up to next+gapLimit, and rebuilds the filter if there were new ones.
+func (w *Wallet) extend() error {
+	end := uint32(1 << 31)
+	if next := uint64(w.next) + uint64(w.gapLimit); next < uint64(end) {
+		end = uint32(next)
+	}
+	if end <= w.end && w.filter != nil {
+		return nil
+	}
+	for i := w.end; i < end; i++ {
+		child, ok, err := w.Child(i)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		addr, err := w.addr(child)
+		if err != nil {
+			return fmt.Errorf("watch: child %d: %w", i, err)
+		}
+		w.indices[addr] = i
+	}
+	w.end = end
+
+	items := make([]string, 0, len(w.indices))
+	for addr := range w.indices {
+		items = append(items, addr)
+	}
+	// the filter is rebuilt rather than grown, so that it only depends on the watched addresses
+	f, err := cuckoo.NewCanonicalCuckooFilter(items, FalsePositiveRate)
+	if err != nil {
+		return fmt.Errorf("watch: %w", err)
+	}
+	w.filter = f
+	return nil
+}
@@ -0,0 +1,100 @@
+package watch
+
+import (
+	"crypto/rand"
+	mrand "math/rand"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taurusgroup/multi-party-sig/internal/test"
+	"github.com/taurusgroup/multi-party-sig/pkg/address"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/sample"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+	"github.com/taurusgroup/multi-party-sig/protocols/frost"
+)
+
+func TestWallet(t *testing.T) {
+	configs, partyIDs := test.GenerateConfig(curve.Secp256k1{}, 3, 1, mrand.New(mrand.NewSource(1)), nil)
+	c := configs[partyIDs[0]]
+
+	w, err := New(c.PublicPoint(), c.ChainKey, 5, Ethereum)
+	require.NoError(t, err)
+	assert.Equal(t, 5, w.Len())
+
+	for i := uint32(0); i < 5; i++ {
+		child, err := c.DeriveBIP32(i)
+		require.NoError(t, err)
+		expected, err := address.Ethereum(child.PublicPoint())
+		require.NoError(t, err)
+
+		j, ok := w.Match(expected.Hex())
+		assert.True(t, ok, "child %d should be watched", i)
+		assert.Equal(t, i, j)
+	}
+
+	far, err := w.Address(9)
+	require.NoError(t, err)
+	_, ok := w.Match(far)
+	assert.False(t, ok, "children past the gap limit should not be watched")
+
+	require.NoError(t, w.Use(4))
+	assert.Equal(t, uint32(5), w.Next())
+	assert.Equal(t, 10, w.Len())
+	i, ok := w.Match(far)
+	assert.True(t, ok, "using a child should slide the window")
+	assert.Equal(t, uint32(9), i)
+
+	_, ok = w.Match("0x0000000000000000000000000000000000000000")
+	assert.False(t, ok)
+	assert.Error(t, w.Use(1<<31))
+}
+
+func TestTaprootWallet(t *testing.T) {
+	group := curve.Secp256k1{}
+	secret := sample.Scalar(rand.Reader, group).(*curve.Secp256k1Scalar)
+	public := secret.ActOnBase().(*curve.Secp256k1Point)
+	if !public.HasEvenY() {
+		secret.Negate()
+		public = public.Negate().(*curve.Secp256k1Point)
+	}
+	chainKey := make([]byte, 32)
+	_, _ = rand.Read(chainKey)
+	c := &frost.TaprootConfig{
+		ID:                 "a",
+		PrivateShare:       secret,
+		PublicKey:          public.XBytes(),
+		ChainKey:           chainKey,
+		VerificationShares: map[party.ID]*curve.Secp256k1Point{"a": public},
+	}
+
+	net := &chaincfg.TestNet3Params
+	w, err := NewTaproot(c.PublicKey, c.ChainKey, DefaultGapLimit, Bitcoin(address.P2TR, net))
+	require.NoError(t, err)
+
+	for _, i := range []uint32{0, 7, DefaultGapLimit - 1} {
+		child, err := c.DeriveChild(i)
+		require.NoError(t, err)
+		_, outputKey, err := child.PublicKey.Tweak(nil)
+		require.NoError(t, err)
+		addr, err := address.PayToTaproot(outputKey, net)
+		require.NoError(t, err)
+
+		pkScript, err := txscript.PayToAddrScript(addr)
+		require.NoError(t, err)
+		j, ok := w.MatchScript(pkScript, net)
+		assert.True(t, ok, "child %d should be watched", i)
+		assert.Equal(t, i, j)
+	}
+
+	other, err := btcutil.NewAddressTaproot(make([]byte, 32), net)
+	require.NoError(t, err)
+	pkScript, err := txscript.PayToAddrScript(other)
+	require.NoError(t, err)
+	_, ok := w.MatchScript(pkScript, net)
+	assert.False(t, ok)
+}
@@ -0,0 +1,178 @@
+// Package attest runs the signing protocols so that they also output a "signed-by" filter,
+// holding the public key shares of the parties who actually took part in signing.
+//
+// The filter is built with filters.Build from the shares of the signers, as found in the
+// configuration every party shares, so all signers output the same filter. It can be published
+// next to the signature, and lets auditors test whether a given key holder took part
+// without revealing the full list of signers.
+package attest
+
+import (
+	"fmt"
+
+	"github.com/taurusgroup/multi-party-sig/filters"
+	_ "github.com/taurusgroup/multi-party-sig/filters/xor"
+	"github.com/taurusgroup/multi-party-sig/pkg/ecdsa"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+	"github.com/taurusgroup/multi-party-sig/pkg/pool"
+	"github.com/taurusgroup/multi-party-sig/pkg/protocol"
+	"github.com/taurusgroup/multi-party-sig/pkg/taproot"
+	"github.com/taurusgroup/multi-party-sig/protocols/cmp"
+	"github.com/taurusgroup/multi-party-sig/protocols/frost"
+)
+
+// Spec selects the filter holding the signers.
+type Spec struct {
+	// Name is the name of a registered filter, see filters.Names.
+	Name string
+	// E is the false positive rate of the filter.
+	E float64
+}
+
+// DefaultSpec uses a binary fuse filter, the smallest filter for a fixed set of items.
+var DefaultSpec = Spec{Name: "xor", E: 1.0 / 256}
+
+// Item returns the filter item of a public key share, which is its binary encoding,
+// i.e. a compressed point for secp256k1.
+func Item(share curve.Point) (string, error) {
+	data, err := share.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// SignedBy builds the filter described by spec, holding the shares of signers.
+func SignedBy(spec Spec, shares map[party.ID]curve.Point, signers []party.ID) (filters.Filter, error) {
+	items := make([]string, 0, len(signers))
+	for _, id := range signers {
+		share, ok := shares[id]
+		if !ok {
+			return nil, fmt.Errorf("attest: no public share for signer %s", id)
+		}
+		item, err := Item(share)
+		if err != nil {
+			return nil, fmt.Errorf("attest: signer %s: %w", id, err)
+		}
+		items = append(items, item)
+	}
+	f, err := filters.Build(spec.Name, items, spec.E)
+	if err != nil {
+		return nil, fmt.Errorf("attest: %w", err)
+	}
+	// static filters are only built when first used, make sure it happens now
+	if _, err := f.MarshalBinary(); err != nil {
+		return nil, fmt.Errorf("attest: %w", err)
+	}
+	return f, nil
+}
+
+// CMPShares returns the ECDSA public shares of every party in a cmp.Config.
+func CMPShares(c *cmp.Config) map[party.ID]curve.Point {
+	shares := make(map[party.ID]curve.Point, len(c.Public))
+	for id, public := range c.Public {
+		shares[id] = public.ECDSA
+	}
+	return shares
+}
+
+// TaprootShares returns the verification shares of every party in a frost.TaprootConfig.
+func TaprootShares(c *frost.TaprootConfig) map[party.ID]curve.Point {
+	shares := make(map[party.ID]curve.Point, len(c.VerificationShares))
+	for id, share := range c.VerificationShares {
+		shares[id] = share
+	}
+	return shares
+}
+
+// Attestation is the signed-by filter output along with a signature.
+type Attestation struct {
+	// Signers are the parties who took part in signing.
+	Signers party.IDSlice
+	// SignedBy holds the public shares of Signers.
+	SignedBy filters.Filter
+}
+
+// Attests reports whether the owner of share may have taken part in signing.
+// Like any filter test, it may return true for a share which didn't.
+func (a *Attestation) Attests(share curve.Point) bool {
+	item, err := Item(share)
+	return err == nil && a.SignedBy.Test(item)
+}
+
+// ECDSASignature is an ECDSA signature along with its signed-by filter.
+type ECDSASignature struct {
+	Attestation
+	Signature *ecdsa.Signature
+}
+
+// SchnorrSignature is a FROST signature along with its signed-by filter.
+type SchnorrSignature struct {
+	Attestation
+	Signature frost.Signature
+}
+
+// TaprootSignature is a BIP-340 signature along with its signed-by filter.
+type TaprootSignature struct {
+	Attestation
+	Signature taproot.Signature
+}
+
+// CMPSign is like cmp.Sign, but also outputs the signed-by filter.
+// Returns *ECDSASignature if successful.
+func CMPSign(config *cmp.Config, signers []party.ID, messageHash []byte, spec Spec, pl *pool.Pool) protocol.StartFunc {
+	a, err := newAttestation(spec, CMPShares(config), signers)
+	if err != nil {
+		return protocol.Failed(err)
+	}
+	return protocol.MapResult(cmp.Sign(config, signers, messageHash, pl), func(result interface{}) interface{} {
+		return &ECDSASignature{Attestation: *a, Signature: result.(*ecdsa.Signature)}
+	})
+}
+
+// CMPPresignOnline is like cmp.PresignOnline, but also outputs the signed-by filter
+// of the parties who generated preSignature.
+// Returns *ECDSASignature if successful.
+func CMPPresignOnline(config *cmp.Config, preSignature *ecdsa.PreSignature, messageHash []byte, spec Spec, pl *pool.Pool) protocol.StartFunc {
+	a, err := newAttestation(spec, CMPShares(config), preSignature.SignerIDs())
+	if err != nil {
+		return protocol.Failed(err)
+	}
+	return protocol.MapResult(cmp.PresignOnline(config, preSignature, messageHash, pl), func(result interface{}) interface{} {
+		return &ECDSASignature{Attestation: *a, Signature: result.(*ecdsa.Signature)}
+	})
+}
+
+// FrostSign is like frost.Sign, but also outputs the signed-by filter.
+// Returns *SchnorrSignature if successful.
+func FrostSign(config *frost.Config, signers []party.ID, messageHash []byte, spec Spec) protocol.StartFunc {
+	a, err := newAttestation(spec, config.VerificationShares.Points, signers)
+	if err != nil {
+		return protocol.Failed(err)
+	}
+	return protocol.MapResult(frost.Sign(config, signers, messageHash), func(result interface{}) interface{} {
+		return &SchnorrSignature{Attestation: *a, Signature: result.(frost.Signature)}
+	})
+}
+
+// FrostSignTaproot is like frost.SignTaproot, but also outputs the signed-by filter.
+// Returns *TaprootSignature if successful.
+func FrostSignTaproot(config *frost.TaprootConfig, signers []party.ID, messageHash []byte, spec Spec) protocol.StartFunc {
+	a, err := newAttestation(spec, TaprootShares(config), signers)
+	if err != nil {
+		return protocol.Failed(err)
+	}
+	return protocol.MapResult(frost.SignTaproot(config, signers, messageHash), func(result interface{}) interface{} {
+		return &TaprootSignature{Attestation: *a, Signature: result.(taproot.Signature)}
+	})
+}
+
+func newAttestation(spec Spec, shares map[party.ID]curve.Point, signers []party.ID) (*Attestation, error) {
+	ids := party.NewIDSlice(signers)
+	f, err := SignedBy(spec, shares, ids)
+	if err != nil {
+		return nil, err
+	}
+	return &Attestation{Signers: ids, SignedBy: f}, nil
+}
@@ -0,0 +1,146 @@
+package attest_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "github.com/taurusgroup/multi-party-sig/filters/bloom"
+	"github.com/taurusgroup/multi-party-sig/internal/test"
+	"github.com/taurusgroup/multi-party-sig/pkg/ecdsa"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+	"github.com/taurusgroup/multi-party-sig/pkg/pool"
+	"github.com/taurusgroup/multi-party-sig/pkg/protocol"
+	"github.com/taurusgroup/multi-party-sig/protocols/attest"
+	"github.com/taurusgroup/multi-party-sig/protocols/cmp"
+	"github.com/taurusgroup/multi-party-sig/protocols/frost"
+)
+
+// run executes the protocol created by start for every party, and returns their results.
+func run(t *testing.T, ids party.IDSlice, start func(id party.ID) protocol.StartFunc) map[party.ID]interface{} {
+	net := test.NewNetwork(ids)
+	results := make(map[party.ID]interface{}, len(ids))
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id party.ID) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(start(id), nil)
+			require.NoError(t, err)
+			test.HandlerLoop(id, h, net)
+			r, err := h.Result()
+			require.NoError(t, err)
+			mtx.Lock()
+			results[id] = r
+			mtx.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return results
+}
+
+// checkAttestations checks that every party output the same filter, attesting the signers.
+func checkAttestations(t *testing.T, attestations []*attest.Attestation, shares map[party.ID]curve.Point, signers party.IDSlice) {
+	var first []byte
+	for _, a := range attestations {
+		assert.Equal(t, signers, a.Signers)
+		data, err := a.SignedBy.MarshalBinary()
+		require.NoError(t, err)
+		if first == nil {
+			first = data
+		}
+		assert.True(t, bytes.Equal(first, data), "parties output different filters")
+		// other parties are only reported with the false positive rate of the filter
+		for _, id := range signers {
+			assert.True(t, a.Attests(shares[id]), "party %s", id)
+		}
+	}
+}
+
+func TestCMP(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+
+	configs, ids := test.GenerateConfig(curve.Secp256k1{}, 4, 1, rand.Reader, pl)
+	shares := attest.CMPShares(configs[ids[0]])
+	public := configs[ids[0]].PublicPoint()
+	messageHash := []byte("hello, auditors")
+	signers := party.IDSlice{ids[3], ids[1]}
+
+	results := run(t, signers, func(id party.ID) protocol.StartFunc {
+		return attest.CMPSign(configs[id], signers, messageHash, attest.DefaultSpec, pl)
+	})
+	var attestations []*attest.Attestation
+	for _, r := range results {
+		require.IsType(t, &attest.ECDSASignature{}, r)
+		s := r.(*attest.ECDSASignature)
+		assert.True(t, s.Signature.Verify(public, messageHash))
+		attestations = append(attestations, &s.Attestation)
+	}
+	checkAttestations(t, attestations, shares, party.NewIDSlice(signers))
+
+	preSignatures := run(t, signers, func(id party.ID) protocol.StartFunc {
+		return cmp.Presign(configs[id], signers, pl)
+	})
+	spec := attest.Spec{Name: "bloom", E: 0.001}
+	results = run(t, signers, func(id party.ID) protocol.StartFunc {
+		return attest.CMPPresignOnline(configs[id], preSignatures[id].(*ecdsa.PreSignature), messageHash, spec, pl)
+	})
+	attestations = nil
+	for _, r := range results {
+		require.IsType(t, &attest.ECDSASignature{}, r)
+		s := r.(*attest.ECDSASignature)
+		assert.True(t, s.Signature.Verify(public, messageHash))
+		attestations = append(attestations, &s.Attestation)
+	}
+	checkAttestations(t, attestations, shares, party.NewIDSlice(signers))
+
+	_, err := attest.SignedBy(attest.DefaultSpec, shares, []party.ID{"unknown"})
+	assert.Error(t, err)
+	_, err = attest.SignedBy(attest.Spec{Name: "unknown"}, shares, ids)
+	assert.Error(t, err)
+}
+
+func TestFrost(t *testing.T) {
+	ids := test.PartyIDs(4)
+	threshold := 1
+	configs := run(t, ids, func(id party.ID) protocol.StartFunc {
+		return frost.Keygen(curve.Secp256k1{}, id, ids, threshold)
+	})
+	taprootConfigs := run(t, ids, func(id party.ID) protocol.StartFunc {
+		return frost.KeygenTaproot(id, ids, threshold)
+	})
+	messageHash := []byte("hello, auditors")
+	signers := ids[1:3]
+
+	c0 := configs[ids[0]].(*frost.Config)
+	results := run(t, signers, func(id party.ID) protocol.StartFunc {
+		return attest.FrostSign(configs[id].(*frost.Config), signers, messageHash, attest.DefaultSpec)
+	})
+	var attestations []*attest.Attestation
+	for _, r := range results {
+		require.IsType(t, &attest.SchnorrSignature{}, r)
+		s := r.(*attest.SchnorrSignature)
+		assert.True(t, s.Signature.Verify(c0.PublicKey, messageHash))
+		attestations = append(attestations, &s.Attestation)
+	}
+	checkAttestations(t, attestations, c0.VerificationShares.Points, signers)
+
+	t0 := taprootConfigs[ids[0]].(*frost.TaprootConfig)
+	results = run(t, signers, func(id party.ID) protocol.StartFunc {
+		return attest.FrostSignTaproot(taprootConfigs[id].(*frost.TaprootConfig), signers, messageHash, attest.DefaultSpec)
+	})
+	attestations = nil
+	for _, r := range results {
+		require.IsType(t, &attest.TaprootSignature{}, r)
+		s := r.(*attest.TaprootSignature)
+		assert.True(t, t0.PublicKey.Verify(s.Signature, messageHash))
+		attestations = append(attestations, &s.Attestation)
+	}
+	checkAttestations(t, attestations, attest.TaprootShares(t0), signers)
+}
@@ -0,0 +1,358 @@
+// Package bitcoin co-signs partially signed Bitcoin transactions (BIP-174) offline,
+// with a cmp.Config for segwit v0 inputs or a frost.TaprootConfig for taproot inputs (BIP-371).
+//
+// For each input of a Packet paying to the threshold key, SigHash computes the BIP-143
+// or BIP-341 signature hash, which the parties sign with SignECDSA or SignTaproot.
+// The resulting signature is then given to FinalizeECDSA or FinalizeTaproot, which fill in
+// the final witness of the input. Once every input is final, Extract returns the signed
+// transaction, after checking each input against its previous output script.
+//
+// Only P2WPKH inputs and P2TR key path inputs are supported. The frost key is the internal key
+// of P2TR inputs, whose output key is its BIP-341 tweak by the merkle root of the input, if the PSBT
+// gives one, and its BIP-86 tweak otherwise, as in address.Bitcoin.
+package bitcoin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/taurusgroup/multi-party-sig/pkg/address"
+	"github.com/taurusgroup/multi-party-sig/pkg/ecdsa"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+	"github.com/taurusgroup/multi-party-sig/pkg/pool"
+	"github.com/taurusgroup/multi-party-sig/pkg/protocol"
+	"github.com/taurusgroup/multi-party-sig/pkg/taproot"
+	"github.com/taurusgroup/multi-party-sig/protocols/cmp"
+	"github.com/taurusgroup/multi-party-sig/protocols/frost"
+)
+
+// Packet is a PSBT, whose inputs all carry their previous output.
+type Packet struct {
+	*psbt.Packet
+}
+
+// NewPacket wraps p, after checking that the previous output of every input is known,
+// since BIP-341 signature hashes commit to all of them.
+func NewPacket(p *psbt.Packet) (*Packet, error) {
+	packet := &Packet{Packet: p}
+	for i := range p.Inputs {
+		if _, err := packet.PrevOut(i); err != nil {
+			return nil, err
+		}
+	}
+	return packet, nil
+}
+
+// Parse reads a serialized PSBT from r, encoded in base64 if b64 is set.
+func Parse(r io.Reader, b64 bool) (*Packet, error) {
+	p, err := psbt.NewFromRawBytes(r, b64)
+	if err != nil {
+		return nil, fmt.Errorf("bitcoin: %w", err)
+	}
+	return NewPacket(p)
+}
+
+// PrevOut returns the output spent by input i, taken from its witness or non-witness UTXO.
+func (p *Packet) PrevOut(i int) (*wire.TxOut, error) {
+	if i < 0 || i >= len(p.Inputs) || i >= len(p.UnsignedTx.TxIn) {
+		return nil, fmt.Errorf("bitcoin: no input %d", i)
+	}
+	in := &p.Inputs[i]
+	if in.WitnessUtxo != nil {
+		return in.WitnessUtxo, nil
+	}
+	if in.NonWitnessUtxo != nil {
+		outPoint := p.UnsignedTx.TxIn[i].PreviousOutPoint
+		if in.NonWitnessUtxo.TxHash() != outPoint.Hash || int(outPoint.Index) >= len(in.NonWitnessUtxo.TxOut) {
+			return nil, fmt.Errorf("bitcoin: input %d: UTXO doesn't match the outpoint", i)
+		}
+		return in.NonWitnessUtxo.TxOut[outPoint.Index], nil
+	}
+	return nil, fmt.Errorf("bitcoin: input %d: missing UTXO", i)
+}
+
+// SigHashType returns the signature hash type of input i: the one set in the PSBT,
+// or else SIGHASH_ALL for segwit v0 and SIGHASH_DEFAULT for taproot.
+func (p *Packet) SigHashType(i int) (txscript.SigHashType, error) {
+	prevOut, err := p.PrevOut(i)
+	if err != nil {
+		return 0, err
+	}
+	if t := p.Inputs[i].SighashType; t != 0 || txscript.IsPayToTaproot(prevOut.PkScript) {
+		return t, nil
+	}
+	return txscript.SigHashAll, nil
+}
+
+// SigHash returns the hash to sign for input i, following BIP-143 for P2WPKH inputs,
+// and BIP-341 for the key path of P2TR inputs.
+func (p *Packet) SigHash(i int) ([]byte, error) {
+	prevOut, err := p.PrevOut(i)
+	if err != nil {
+		return nil, err
+	}
+	hashType, err := p.SigHashType(i)
+	if err != nil {
+		return nil, err
+	}
+	fetcher, err := p.fetcher()
+	if err != nil {
+		return nil, err
+	}
+	sigHashes := txscript.NewTxSigHashes(p.UnsignedTx, fetcher)
+
+	var hash []byte
+	switch {
+	case txscript.IsPayToWitnessPubKeyHash(prevOut.PkScript):
+		hash, err = txscript.CalcWitnessSigHash(prevOut.PkScript, sigHashes, hashType, p.UnsignedTx, i, prevOut.Value)
+	case txscript.IsPayToTaproot(prevOut.PkScript):
+		hash, err = txscript.CalcTaprootSignatureHash(sigHashes, hashType, p.UnsignedTx, i, fetcher)
+	default:
+		return nil, fmt.Errorf("bitcoin: input %d: unsupported script type %v", i, txscript.GetScriptClass(prevOut.PkScript))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bitcoin: input %d: %w", i, err)
+	}
+	return hash, nil
+}
+
+// ECDSAInputs returns the indices of the P2WPKH inputs spending to public, which are not final yet.
+func (p *Packet) ECDSAInputs(public curve.Point) ([]int, error) {
+	pubKey, err := address.Compressed(public)
+	if err != nil {
+		return nil, fmt.Errorf("bitcoin: %w", err)
+	}
+	pkScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(btcutil.Hash160(pubKey)).Script()
+	if err != nil {
+		return nil, fmt.Errorf("bitcoin: %w", err)
+	}
+	var indices []int
+	for i := range p.Inputs {
+		prevOut, err := p.PrevOut(i)
+		if err == nil && bytes.Equal(prevOut.PkScript, pkScript) && !p.isFinal(i) {
+			indices = append(indices, i)
+		}
+	}
+	return indices, nil
+}
+
+// TaprootInputs returns the indices of the P2TR inputs with internal key internal, which are not final yet,
+// see MerkleRoot.
+func (p *Packet) TaprootInputs(internal taproot.PublicKey) ([]int, error) {
+	if _, err := (curve.Secp256k1{}).LiftX(internal); len(internal) != 32 || err != nil {
+		return nil, fmt.Errorf("bitcoin: invalid taproot internal key %x", []byte(internal))
+	}
+	var indices []int
+	for i := range p.Inputs {
+		if _, err := p.MerkleRoot(i, internal); err == nil && !p.isFinal(i) {
+			indices = append(indices, i)
+		}
+	}
+	return indices, nil
+}
+
+// MerkleRoot returns the merkle root of the script tree the output key of P2TR input i commits to,
+// which is empty for a BIP-86 output without script path.
+//
+// It fails unless the output key is the BIP-341 tweak of internal by this root, so that a signature
+// for the config returned by frost.TaprootConfig.Tweak(root) spends the key path. The PSBT fields
+// of BIP-371 are honored: the input must not have an internal key other than internal,
+// and the merkle root is PSBT_IN_TAP_MERKLE_ROOT if set.
+func (p *Packet) MerkleRoot(i int, internal taproot.PublicKey) ([]byte, error) {
+	prevOut, err := p.PrevOut(i)
+	if err != nil {
+		return nil, err
+	}
+	if !txscript.IsPayToTaproot(prevOut.PkScript) {
+		return nil, fmt.Errorf("bitcoin: input %d: not a taproot input", i)
+	}
+	in := &p.Inputs[i]
+	if key := p.internalKey(i); key != nil && !bytes.Equal(key, internal) {
+		return nil, fmt.Errorf("bitcoin: input %d: internal key is %x", i, key)
+	}
+	_, outputKey, err := internal.Tweak(in.TaprootMerkleRoot)
+	if err != nil {
+		return nil, fmt.Errorf("bitcoin: input %d: %w", i, err)
+	}
+	// the script is OP_1 followed by a push of the 32 byte output key
+	if !bytes.Equal(prevOut.PkScript[2:], outputKey) {
+		return nil, fmt.Errorf("bitcoin: input %d: output key isn't a tweak of the internal key", i)
+	}
+	return in.TaprootMerkleRoot, nil
+}
+
+// internalKey returns the internal key of input i set in the PSBT, or nil: PSBT_IN_TAP_INTERNAL_KEY,
+// or else the key of a PSBT_IN_TAP_BIP32_DERIVATION without leaf hashes, which BIP-371 uses for the internal key.
+func (p *Packet) internalKey(i int) []byte {
+	in := &p.Inputs[i]
+	if len(in.TaprootInternalKey) != 0 {
+		return in.TaprootInternalKey
+	}
+	for _, derivation := range in.TaprootBip32Derivation {
+		if len(derivation.LeafHashes) == 0 {
+			return derivation.XOnlyPubKey
+		}
+	}
+	return nil
+}
+
+// SignECDSA is like cmp.Sign, for the signature hash of input i.
+// Returns *ecdsa.Signature if successful, which should be given to FinalizeECDSA.
+func (p *Packet) SignECDSA(i int, config *cmp.Config, signers []party.ID, pl *pool.Pool) protocol.StartFunc {
+	hash, err := p.SigHash(i)
+	if err != nil {
+		return protocol.Failed(err)
+	}
+	return cmp.Sign(config, signers, hash, pl)
+}
+
+// PresignOnline is like cmp.PresignOnline, for the signature hash of input i.
+// Returns *ecdsa.Signature if successful, which should be given to FinalizeECDSA.
+func (p *Packet) PresignOnline(i int, config *cmp.Config, preSignature *ecdsa.PreSignature, pl *pool.Pool) protocol.StartFunc {
+	hash, err := p.SigHash(i)
+	if err != nil {
+		return protocol.Failed(err)
+	}
+	return cmp.PresignOnline(config, preSignature, hash, pl)
+}
+
+// SignTaproot is like frost.SignTaproot, for the signature hash of input i, where config holds the
+// internal key. The config is tweaked to the output key of the input, see MerkleRoot.
+// Returns taproot.Signature if successful, which should be given to FinalizeTaproot.
+func (p *Packet) SignTaproot(i int, config *frost.TaprootConfig, signers []party.ID) protocol.StartFunc {
+	merkleRoot, err := p.MerkleRoot(i, config.PublicKey)
+	if err != nil {
+		return protocol.Failed(err)
+	}
+	tweaked, err := config.Tweak(merkleRoot)
+	if err != nil {
+		return protocol.Failed(fmt.Errorf("bitcoin: input %d: %w", i, err))
+	}
+	hash, err := p.SigHash(i)
+	if err != nil {
+		return protocol.Failed(err)
+	}
+	return frost.SignTaproot(tweaked, signers, hash)
+}
+
+// FinalizeECDSA checks sig against the signature hash of input i and public,
+// adds it as a partial signature, and fills in the final witness of the input.
+func (p *Packet) FinalizeECDSA(i int, sig *ecdsa.Signature, public curve.Point) error {
+	hash, err := p.SigHash(i)
+	if err != nil {
+		return err
+	}
+	if sig == nil || !sig.Verify(public, hash) {
+		return fmt.Errorf("bitcoin: input %d: invalid signature", i)
+	}
+	hashType, err := p.SigHashType(i)
+	if err != nil {
+		return err
+	}
+	der, err := sig.DER()
+	if err != nil {
+		return fmt.Errorf("bitcoin: %w", err)
+	}
+	pubKey, err := address.Compressed(public)
+	if err != nil {
+		return fmt.Errorf("bitcoin: %w", err)
+	}
+
+	u, err := psbt.NewUpdater(p.Packet)
+	if err != nil {
+		return fmt.Errorf("bitcoin: %w", err)
+	}
+	if _, err = u.Sign(i, append(der, byte(hashType)), pubKey, nil, nil); err != nil {
+		return fmt.Errorf("bitcoin: input %d: %w", i, err)
+	}
+	return p.finalize(i)
+}
+
+// FinalizeTaproot checks sig against the signature hash of input i and its output key,
+// which must be a tweak of internal, sets it as the key path signature,
+// and fills in the final witness of the input.
+func (p *Packet) FinalizeTaproot(i int, sig taproot.Signature, internal taproot.PublicKey) error {
+	merkleRoot, err := p.MerkleRoot(i, internal)
+	if err != nil {
+		return err
+	}
+	_, outputKey, err := internal.Tweak(merkleRoot)
+	if err != nil {
+		return fmt.Errorf("bitcoin: input %d: %w", i, err)
+	}
+	hash, err := p.SigHash(i)
+	if err != nil {
+		return err
+	}
+	if !outputKey.Verify(sig, hash) {
+		return fmt.Errorf("bitcoin: input %d: invalid signature", i)
+	}
+	hashType, err := p.SigHashType(i)
+	if err != nil {
+		return err
+	}
+	keySpendSig := append([]byte{}, sig...)
+	if hashType != txscript.SigHashDefault {
+		keySpendSig = append(keySpendSig, byte(hashType))
+	}
+	p.Inputs[i].TaprootKeySpendSig = keySpendSig
+	return p.finalize(i)
+}
+
+func (p *Packet) finalize(i int) error {
+	if err := psbt.Finalize(p.Packet, i); err != nil {
+		return fmt.Errorf("bitcoin: input %d: %w", i, err)
+	}
+	return nil
+}
+
+// Extract returns the signed transaction, once every input is final,
+// and checks that each input satisfies the script of its previous output.
+func (p *Packet) Extract() (*wire.MsgTx, error) {
+	tx, err := psbt.Extract(p.Packet)
+	if err != nil {
+		return nil, fmt.Errorf("bitcoin: %w", err)
+	}
+	fetcher, err := p.fetcher()
+	if err != nil {
+		return nil, err
+	}
+	sigHashes := txscript.NewTxSigHashes(tx, fetcher)
+	for i := range tx.TxIn {
+		prevOut, err := p.PrevOut(i)
+		if err != nil {
+			return nil, err
+		}
+		vm, err := txscript.NewEngine(prevOut.PkScript, tx, i, txscript.StandardVerifyFlags, nil, sigHashes, prevOut.Value, fetcher)
+		if err != nil {
+			return nil, fmt.Errorf("bitcoin: input %d: %w", i, err)
+		}
+		if err = vm.Execute(); err != nil {
+			return nil, fmt.Errorf("bitcoin: input %d: %w", i, err)
+		}
+	}
+	return tx, nil
+}
+
+func (p *Packet) isFinal(i int) bool {
+	in := &p.Inputs[i]
+	return len(in.FinalScriptSig) != 0 || len(in.FinalScriptWitness) != 0
+}
+
+func (p *Packet) fetcher() (*txscript.MultiPrevOutFetcher, error) {
+	fetcher := txscript.NewMultiPrevOutFetcher(nil)
+	for i, in := range p.UnsignedTx.TxIn {
+		prevOut, err := p.PrevOut(i)
+		if err != nil {
+			return nil, err
+		}
+		fetcher.AddPrevOut(in.PreviousOutPoint, prevOut)
+	}
+	return fetcher, nil
+}
@@ -0,0 +1,180 @@
+package bitcoin_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taurusgroup/multi-party-sig/internal/test"
+	"github.com/taurusgroup/multi-party-sig/pkg/address"
+	"github.com/taurusgroup/multi-party-sig/pkg/ecdsa"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+	"github.com/taurusgroup/multi-party-sig/pkg/pool"
+	"github.com/taurusgroup/multi-party-sig/pkg/protocol"
+	"github.com/taurusgroup/multi-party-sig/pkg/taproot"
+	"github.com/taurusgroup/multi-party-sig/protocols/bitcoin"
+	"github.com/taurusgroup/multi-party-sig/protocols/frost"
+)
+
+// run executes the protocol created by start for every party, and returns their results.
+func run(t *testing.T, ids party.IDSlice, start func(id party.ID) protocol.StartFunc) map[party.ID]interface{} {
+	net := test.NewNetwork(ids)
+	results := make(map[party.ID]interface{}, len(ids))
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id party.ID) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(start(id), nil)
+			require.NoError(t, err)
+			test.HandlerLoop(id, h, net)
+			r, err := h.Result()
+			require.NoError(t, err)
+			mtx.Lock()
+			results[id] = r
+			mtx.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return results
+}
+
+func TestPacket(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+
+	configs, ids := test.GenerateConfig(curve.Secp256k1{}, 3, 1, rand.Reader, pl)
+	public := configs[ids[0]].PublicPoint()
+	taprootConfigs := run(t, ids, func(id party.ID) protocol.StartFunc {
+		return frost.KeygenTaproot(id, ids, 1)
+	})
+	taprootPublic := taprootConfigs[ids[0]].(*frost.TaprootConfig).PublicKey
+
+	net := &chaincfg.TestNet3Params
+	segwitAddr, err := address.PayToWitnessPubKeyHash(public, net)
+	require.NoError(t, err)
+	segwitScript, err := txscript.PayToAddrScript(segwitAddr)
+	require.NoError(t, err)
+	internalKey, err := curve.Secp256k1{}.LiftX(taprootPublic)
+	require.NoError(t, err)
+	taprootAddr, err := address.Bitcoin(address.P2TR, internalKey, net)
+	require.NoError(t, err)
+	taprootScript, err := txscript.PayToAddrScript(taprootAddr)
+	require.NoError(t, err)
+	// an output which also commits to a script tree, only known from the PSBT
+	merkleRoot := chainhash.HashB([]byte("script tree"))
+	_, scriptTreeKey, err := taprootPublic.Tweak(merkleRoot)
+	require.NoError(t, err)
+	scriptTreeAddr, err := address.PayToTaproot(scriptTreeKey, net)
+	require.NoError(t, err)
+	scriptTreeScript, err := txscript.PayToAddrScript(scriptTreeAddr)
+	require.NoError(t, err)
+
+	// a previous transaction funding the wallet, given as non-witness UTXO for the first input
+	funding := wire.NewMsgTx(2)
+	funding.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 0}, nil, nil))
+	funding.AddTxOut(wire.NewTxOut(50_000, segwitScript))
+	fundingHash := funding.TxHash()
+
+	outPoints := []*wire.OutPoint{
+		{Hash: fundingHash, Index: 0},
+		{Hash: chainhash.Hash{1}, Index: 1},
+		{Hash: chainhash.Hash{2}, Index: 0},
+	}
+	outputs := []*wire.TxOut{wire.NewTxOut(139_000, taprootScript)}
+	p, err := psbt.New(outPoints, outputs, 2, 0, []uint32{wire.MaxTxInSequenceNum, wire.MaxTxInSequenceNum, wire.MaxTxInSequenceNum})
+	require.NoError(t, err)
+	u, err := psbt.NewUpdater(p)
+	require.NoError(t, err)
+	require.NoError(t, u.AddInNonWitnessUtxo(funding, 0))
+	require.NoError(t, u.AddInWitnessUtxo(wire.NewTxOut(40_000, taprootScript), 1))
+	require.NoError(t, u.AddInWitnessUtxo(wire.NewTxOut(60_000, scriptTreeScript), 2))
+	require.NoError(t, u.AddInSighashType(txscript.SigHashAll, 2))
+	p.Inputs[2].TaprootInternalKey = taprootPublic
+	p.Inputs[2].TaprootMerkleRoot = merkleRoot
+
+	// the PSBT is handed over in base64, as between wallets
+	encoded, err := p.B64Encode()
+	require.NoError(t, err)
+	packet, err := bitcoin.Parse(strings.NewReader(encoded), true)
+	require.NoError(t, err)
+
+	ecdsaInputs, err := packet.ECDSAInputs(public)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0}, ecdsaInputs)
+	taprootInputs, err := packet.TaprootInputs(taprootPublic)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, taprootInputs)
+	_, err = packet.TaprootInputs(taprootPublic[1:])
+	assert.Error(t, err)
+
+	// an input whose internal key belongs to someone else is not ours, even if the output key matches
+	otherKey := curve.Secp256k1{}.NewBasePoint().(*curve.Secp256k1Point).XBytes()
+	packet.Inputs[1].TaprootInternalKey = otherKey
+	others, err := packet.TaprootInputs(taprootPublic)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, others)
+	packet.Inputs[1].TaprootInternalKey = nil
+
+	hashType, err := packet.SigHashType(0)
+	require.NoError(t, err)
+	assert.Equal(t, txscript.SigHashAll, hashType)
+	hashType, err = packet.SigHashType(1)
+	require.NoError(t, err)
+	assert.Equal(t, txscript.SigHashDefault, hashType)
+
+	signers := ids[:2]
+	results := run(t, signers, func(id party.ID) protocol.StartFunc {
+		return packet.SignECDSA(0, configs[id], signers, pl)
+	})
+	sig := results[signers[0]].(*ecdsa.Signature)
+	assert.Error(t, packet.FinalizeECDSA(0, sig, curve.Secp256k1{}.NewBasePoint()))
+	require.NoError(t, packet.FinalizeECDSA(0, sig, public))
+	ecdsaInputs, err = packet.ECDSAInputs(public)
+	require.NoError(t, err)
+	assert.Empty(t, ecdsaInputs, "final inputs should not be listed")
+
+	for _, i := range taprootInputs {
+		results := run(t, signers, func(id party.ID) protocol.StartFunc {
+			return packet.SignTaproot(i, taprootConfigs[id].(*frost.TaprootConfig), signers)
+		})
+		sig := results[signers[0]].(taproot.Signature)
+		if i == taprootInputs[0] {
+			_, err := packet.Extract()
+			assert.Error(t, err, "incomplete packet should not be extracted")
+			assert.Error(t, packet.FinalizeTaproot(taprootInputs[1], sig, taprootPublic), "signature is for another input")
+		}
+		require.NoError(t, packet.FinalizeTaproot(i, sig, taprootPublic))
+	}
+
+	tx, err := packet.Extract()
+	require.NoError(t, err)
+	assert.Len(t, tx.TxIn[0].Witness, 2)
+	assert.Len(t, tx.TxIn[1].Witness[0], 64)
+	assert.Len(t, tx.TxIn[2].Witness[0], 65, "non-default sighash type should be appended")
+
+	var buf bytes.Buffer
+	require.NoError(t, tx.Serialize(&buf))
+	decoded := wire.NewMsgTx(2)
+	require.NoError(t, decoded.Deserialize(&buf))
+	assert.Equal(t, tx.TxHash(), decoded.TxHash())
+	assert.Equal(t, p.UnsignedTx.TxHash(), tx.TxHash(), "signing should not change the txid")
+}
+
+func TestParseMissingUTXO(t *testing.T) {
+	p, err := psbt.New([]*wire.OutPoint{{Index: 0}}, []*wire.TxOut{wire.NewTxOut(1, []byte{txscript.OP_TRUE})}, 2, 0, []uint32{0})
+	require.NoError(t, err)
+	_, err = bitcoin.NewPacket(p)
+	assert.Error(t, err)
+}
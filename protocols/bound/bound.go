@@ -0,0 +1,137 @@
+// Package bound signs a message together with a membership filter, so that a verifier
+// knows the filter was approved by the same quorum as the message.
+//
+// Instead of messageHash, the signers sign
+//
+//	H(domain, messageHash, filterDigest)
+//
+// where filterDigest is filters.Digest of the filter. The results carry the message hash and
+// the encoded filter, and their Verify methods check the signature and the binding together.
+package bound
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/taurusgroup/multi-party-sig/filters"
+	"github.com/taurusgroup/multi-party-sig/pkg/ecdsa"
+	"github.com/taurusgroup/multi-party-sig/pkg/hash"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+	"github.com/taurusgroup/multi-party-sig/pkg/pool"
+	"github.com/taurusgroup/multi-party-sig/pkg/protocol"
+	"github.com/taurusgroup/multi-party-sig/protocols/cmp"
+	"github.com/taurusgroup/multi-party-sig/protocols/frost"
+)
+
+// Domain separates filter-bound hashes from any other use of the hash function.
+const Domain = "Filter Bound Signature"
+
+// HashSize is the size of the hash which is actually signed.
+const HashSize = 32
+
+// Binding is a message hash along with the encoding of the filter it is bound to.
+type Binding struct {
+	MessageHash []byte
+	// Filter is the encoding of the filter, as returned by its MarshalBinary method.
+	Filter []byte
+}
+
+// NewBinding binds messageHash to f.
+func NewBinding(messageHash []byte, f filters.Filter) (*Binding, error) {
+	if len(messageHash) == 0 {
+		return nil, errors.New("bound: empty message hash")
+	}
+	data, err := f.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &Binding{MessageHash: messageHash, Filter: data}, nil
+}
+
+// Hash returns H(Domain, MessageHash, filterDigest), which is what the signers sign.
+func (b *Binding) Hash() []byte {
+	return Hash(b.MessageHash, filters.DigestBytes(b.Filter))
+}
+
+// Matches reports whether f has the same encoding as the bound filter.
+func (b *Binding) Matches(f filters.Filter) bool {
+	data, err := f.MarshalBinary()
+	return err == nil && bytes.Equal(data, b.Filter)
+}
+
+// Hash returns H(Domain, messageHash, filterDigest).
+func Hash(messageHash, filterDigest []byte) []byte {
+	h := hash.New(
+		hash.BytesWithDomain{TheDomain: "Domain", Bytes: []byte(Domain)},
+		hash.BytesWithDomain{TheDomain: "Message Hash", Bytes: messageHash},
+		hash.BytesWithDomain{TheDomain: "Filter Digest", Bytes: filterDigest},
+	)
+	return h.Sum()[:HashSize]
+}
+
+// ECDSASignature is an ECDSA signature of a Binding.
+type ECDSASignature struct {
+	*Binding
+	Signature *ecdsa.Signature
+}
+
+// Verify checks that the signature is valid for public and the bound message and filter.
+func (s *ECDSASignature) Verify(public curve.Point) bool {
+	if s == nil || s.Binding == nil || s.Signature == nil {
+		return false
+	}
+	return s.Signature.Verify(public, s.Hash())
+}
+
+// SchnorrSignature is a FROST signature of a Binding.
+type SchnorrSignature struct {
+	*Binding
+	Signature frost.Signature
+}
+
+// Verify checks that the signature is valid for public and the bound message and filter.
+func (s *SchnorrSignature) Verify(public curve.Point) bool {
+	if s == nil || s.Binding == nil || s.Signature.R == nil {
+		return false
+	}
+	return s.Signature.Verify(public, s.Hash())
+}
+
+// CMPSign is like cmp.Sign, but signs messageHash bound to f.
+// Returns *ECDSASignature if successful.
+func CMPSign(config *cmp.Config, signers []party.ID, messageHash []byte, f filters.Filter, pl *pool.Pool) protocol.StartFunc {
+	b, err := NewBinding(messageHash, f)
+	if err != nil {
+		return protocol.Failed(err)
+	}
+	return withECDSAResult(cmp.Sign(config, signers, b.Hash(), pl), b)
+}
+
+// CMPPresignOnline is like cmp.PresignOnline, but signs messageHash bound to f.
+// Returns *ECDSASignature if successful.
+func CMPPresignOnline(config *cmp.Config, preSignature *ecdsa.PreSignature, messageHash []byte, f filters.Filter, pl *pool.Pool) protocol.StartFunc {
+	b, err := NewBinding(messageHash, f)
+	if err != nil {
+		return protocol.Failed(err)
+	}
+	return withECDSAResult(cmp.PresignOnline(config, preSignature, b.Hash(), pl), b)
+}
+
+// FrostSign is like frost.Sign, but signs messageHash bound to f.
+// Returns *SchnorrSignature if successful.
+func FrostSign(config *frost.Config, signers []party.ID, messageHash []byte, f filters.Filter) protocol.StartFunc {
+	b, err := NewBinding(messageHash, f)
+	if err != nil {
+		return protocol.Failed(err)
+	}
+	return protocol.MapResult(frost.Sign(config, signers, b.Hash()), func(result interface{}) interface{} {
+		return &SchnorrSignature{Binding: b, Signature: result.(frost.Signature)}
+	})
+}
+
+func withECDSAResult(start protocol.StartFunc, b *Binding) protocol.StartFunc {
+	return protocol.MapResult(start, func(result interface{}) interface{} {
+		return &ECDSASignature{Binding: b, Signature: result.(*ecdsa.Signature)}
+	})
+}
@@ -0,0 +1,118 @@
+package bound_test
+
+import (
+	"crypto/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taurusgroup/multi-party-sig/filters"
+	_ "github.com/taurusgroup/multi-party-sig/filters/cuckoo"
+	"github.com/taurusgroup/multi-party-sig/internal/test"
+	"github.com/taurusgroup/multi-party-sig/pkg/ecdsa"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+	"github.com/taurusgroup/multi-party-sig/pkg/pool"
+	"github.com/taurusgroup/multi-party-sig/pkg/protocol"
+	"github.com/taurusgroup/multi-party-sig/protocols/bound"
+	"github.com/taurusgroup/multi-party-sig/protocols/cmp"
+	"github.com/taurusgroup/multi-party-sig/protocols/frost"
+)
+
+// run executes the protocol created by start for every party, and returns their results.
+func run(t *testing.T, ids party.IDSlice, start func(id party.ID) protocol.StartFunc) map[party.ID]interface{} {
+	net := test.NewNetwork(ids)
+	results := make(map[party.ID]interface{}, len(ids))
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id party.ID) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(start(id), nil)
+			require.NoError(t, err)
+			test.HandlerLoop(id, h, net)
+			r, err := h.Result()
+			require.NoError(t, err)
+			mtx.Lock()
+			results[id] = r
+			mtx.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return results
+}
+
+func TestBoundSignatures(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+
+	configs, ids := test.GenerateConfig(curve.Secp256k1{}, 3, 1, rand.Reader, pl)
+	public := configs[ids[0]].PublicPoint()
+	messageHash := []byte("hello, filter")
+	f, err := filters.Build("cuckoo", []string{"alice", "bob", "carol"}, 0.01)
+	require.NoError(t, err)
+	other, err := filters.Build("cuckoo", []string{"alice", "bob", "mallory"}, 0.01)
+	require.NoError(t, err)
+
+	check := func(s *bound.ECDSASignature) {
+		assert.True(t, s.Verify(public))
+		assert.True(t, s.Matches(f))
+		assert.False(t, s.Matches(other))
+		// the signature doesn't verify for the plain message, or another filter
+		assert.False(t, s.Signature.Verify(public, messageHash))
+		tampered := *s
+		tampered.Binding, err = bound.NewBinding(messageHash, other)
+		require.NoError(t, err)
+		assert.False(t, tampered.Verify(public))
+	}
+
+	signers := ids[:2]
+	results := run(t, signers, func(id party.ID) protocol.StartFunc {
+		return bound.CMPSign(configs[id], signers, messageHash, f, pl)
+	})
+	for _, r := range results {
+		require.IsType(t, &bound.ECDSASignature{}, r)
+		check(r.(*bound.ECDSASignature))
+	}
+
+	preSignatures := run(t, signers, func(id party.ID) protocol.StartFunc {
+		return cmp.Presign(configs[id], signers, pl)
+	})
+	results = run(t, signers, func(id party.ID) protocol.StartFunc {
+		return bound.CMPPresignOnline(configs[id], preSignatures[id].(*ecdsa.PreSignature), messageHash, f, pl)
+	})
+	for _, r := range results {
+		require.IsType(t, &bound.ECDSASignature{}, r)
+		check(r.(*bound.ECDSASignature))
+	}
+
+	// frost shares are the same Shamir shares as cmp's
+	shares := make(map[party.ID]curve.Point, len(ids))
+	for _, id := range ids {
+		shares[id] = configs[ids[0]].Public[id].ECDSA
+	}
+	results = run(t, signers, func(id party.ID) protocol.StartFunc {
+		c := &frost.Config{
+			ID:                 id,
+			Threshold:          configs[id].Threshold,
+			PrivateShare:       configs[id].ECDSA,
+			PublicKey:          public,
+			VerificationShares: party.NewPointMap(shares),
+		}
+		return bound.FrostSign(c, signers, messageHash, f)
+	})
+	for _, r := range results {
+		require.IsType(t, &bound.SchnorrSignature{}, r)
+		s := r.(*bound.SchnorrSignature)
+		assert.True(t, s.Verify(public))
+		assert.False(t, s.Signature.Verify(public, messageHash))
+	}
+}
+
+func TestHash(t *testing.T) {
+	h := bound.Hash([]byte("message"), []byte("digest"))
+	assert.Len(t, h, bound.HashSize)
+	assert.NotEqual(t, h, bound.Hash([]byte("messaged"), []byte("igest")))
+}
@@ -59,6 +59,25 @@ func Refresh(config *Config, pl *pool.Pool) protocol.StartFunc {
 	return keygen.Start(info, pl, config)
 }
 
+// RefreshMembers is like Refresh, but also replaces the allow list of the config by one holding exactly members,
+// which must be parties of the config. Parties which are no longer members are deleted from the filter
+// if it supports it, otherwise a new filter is built, see allowlist.AllowList.Update.
+//
+// All parties must use the same members. They check that they obtained the same filter
+// by broadcasting its digest in an additional round.
+// Returns *cmp.Config if successful, with the new filter as its AllowList.
+func RefreshMembers(config *Config, members []party.ID, pl *pool.Pool) protocol.StartFunc {
+	info := round.Info{
+		ProtocolID:       "cmp/refresh-members",
+		FinalRoundNumber: keygen.MembershipRounds,
+		SelfID:           config.ID,
+		PartyIDs:         config.PartyIDs(),
+		Threshold:        config.Threshold,
+		Group:            config.Group,
+	}
+	return keygen.StartMembership(info, pl, config, members)
+}
+
 // Sign generates an ECDSA signature for `messageHash` among the given `signers`.
 // Returns *ecdsa.Signature if successful.
 func Sign(config *Config, signers []party.ID, messageHash []byte, pl *pool.Pool) protocol.StartFunc {
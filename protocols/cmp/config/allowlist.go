@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/taurusgroup/multi-party-sig/pkg/allowlist"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+)
+
+// CheckAllowList returns an error if one of the signers is not in the allow list of the config.
+// It always succeeds for configs without an allow list.
+func (c *Config) CheckAllowList(signers []party.ID) error {
+	if c.AllowList == nil {
+		return nil
+	}
+	if c.AllowList.Filter == nil {
+		return fmt.Errorf("config: %w", allowlist.ErrNoFilter)
+	}
+	for _, id := range signers {
+		if !c.AllowList.Allows(id) {
+			return fmt.Errorf("config: party %s is not in the allow list", id)
+		}
+	}
+	return nil
+}
@@ -6,9 +6,11 @@ import (
 	"io"
 	"math"
 
+	"github.com/taurusgroup/multi-party-sig/filters"
 	"github.com/taurusgroup/multi-party-sig/internal/bip32"
 	"github.com/taurusgroup/multi-party-sig/internal/params"
 	"github.com/taurusgroup/multi-party-sig/internal/types"
+	"github.com/taurusgroup/multi-party-sig/pkg/allowlist"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/polynomial"
 	"github.com/taurusgroup/multi-party-sig/pkg/paillier"
@@ -42,6 +44,8 @@ type Config struct {
 	ChainKey types.RID
 	// Public maps party.ID to public. It contains all public information associated to a party.
 	Public map[party.ID]*Public
+	// AllowList optionally restricts the parties allowed to sign, see CanSign.
+	AllowList *allowlist.AllowList
 }
 
 // Public holds public information for a party.
@@ -117,6 +121,21 @@ func (c *Config) WriteTo(w io.Writer) (total int64, err error) {
 			return
 		}
 	}
+
+	// write the allow list, so that all signers must agree on it
+	if c.AllowList != nil {
+		if c.AllowList.Filter == nil {
+			return total, fmt.Errorf("config: %w", allowlist.ErrNoFilter)
+		}
+		var digest []byte
+		digest, err = filters.Digest(c.AllowList.Filter)
+		if err != nil {
+			return
+		}
+		var m int
+		m, err = w.Write(digest)
+		total += int64(m)
+	}
 	return
 }
 
@@ -174,7 +193,7 @@ func (p *Public) WriteTo(w io.Writer) (total int64, err error) {
 
 // CanSign returns true if the given _sorted_ list of signers is
 // a valid subset of the original parties of size > t,
-// and includes self.
+// includes self, and only contains parties in the allow list if there is one.
 func (c *Config) CanSign(signers party.IDSlice) bool {
 	if !ValidThreshold(c.Threshold, len(signers)) {
 		return false
@@ -197,7 +216,7 @@ func (c *Config) CanSign(signers party.IDSlice) bool {
 		}
 	}
 
-	return true
+	return c.CheckAllowList(signers) == nil
 }
 
 func ValidThreshold(t, n int) bool {
@@ -238,6 +257,15 @@ func (c *Config) Derive(adjust curve.Scalar, newChainKey []byte) (*Config, error
 		}
 	}
 
+	// the derived config gets its own allow list, so that suspending a party only affects one of them
+	var allowList *allowlist.AllowList
+	if c.AllowList != nil {
+		var err error
+		if allowList, err = c.AllowList.Copy(); err != nil {
+			return nil, err
+		}
+	}
+
 	return &Config{
 		Group:     c.Group,
 		ID:        c.ID,
@@ -248,6 +276,7 @@ func (c *Config) Derive(adjust curve.Scalar, newChainKey []byte) (*Config, error
 		RID:       c.RID,
 		ChainKey:  newChainKey,
 		Public:    public,
+		AllowList: allowList,
 	}, nil
 }
 
@@ -6,7 +6,9 @@ import (
 
 	"github.com/cronokirby/saferith"
 	"github.com/fxamacker/cbor/v2"
+	"github.com/taurusgroup/multi-party-sig/filters"
 	"github.com/taurusgroup/multi-party-sig/internal/types"
+	"github.com/taurusgroup/multi-party-sig/pkg/allowlist"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
 	"github.com/taurusgroup/multi-party-sig/pkg/paillier"
 	"github.com/taurusgroup/multi-party-sig/pkg/party"
@@ -30,6 +32,11 @@ type configMarshal struct {
 	P, Q           *saferith.Nat
 	RID, ChainKey  types.RID
 	Public         []cbor.RawMessage
+	// AllowListName, AllowList and AllowListRate are omitted for configs without an allow list,
+	// so that their encoding is unchanged.
+	AllowListName string  `cbor:",omitempty"`
+	AllowList     []byte  `cbor:",omitempty"`
+	AllowListRate float64 `cbor:",omitempty"`
 }
 
 type publicMarshal struct {
@@ -57,7 +64,7 @@ func (c *Config) MarshalBinary() ([]byte, error) {
 		}
 		ps = append(ps, data)
 	}
-	return cbor.Marshal(&configMarshal{
+	cm := &configMarshal{
 		ID:        c.ID,
 		Threshold: c.Threshold,
 		ECDSA:     c.ECDSA,
@@ -67,7 +74,20 @@ func (c *Config) MarshalBinary() ([]byte, error) {
 		RID:       c.RID,
 		ChainKey:  c.ChainKey,
 		Public:    ps,
-	})
+	}
+	if c.AllowList != nil {
+		if c.AllowList.Filter == nil {
+			return nil, fmt.Errorf("config: %w", allowlist.ErrNoFilter)
+		}
+		data, err := c.AllowList.Filter.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("config: allow list: %w", err)
+		}
+		cm.AllowListName = c.AllowList.Name
+		cm.AllowList = data
+		cm.AllowListRate = c.AllowList.E
+	}
+	return cbor.Marshal(cm)
 }
 
 func (c *Config) UnmarshalBinary(data []byte) error {
@@ -151,6 +171,15 @@ func (c *Config) UnmarshalBinary(data []byte) error {
 		return errors.New("config: no public data for this party")
 	}
 
+	var allowList *allowlist.AllowList
+	if cm.AllowListName != "" {
+		f, err := filters.Decode(cm.AllowListName, cm.AllowList)
+		if err != nil {
+			return fmt.Errorf("config: allow list: %w", err)
+		}
+		allowList = &allowlist.AllowList{Name: cm.AllowListName, Filter: f, E: cm.AllowListRate}
+	}
+
 	*c = Config{
 		Group:     c.Group,
 		ID:        cm.ID,
@@ -161,6 +190,7 @@ func (c *Config) UnmarshalBinary(data []byte) error {
 		RID:       cm.RID,
 		ChainKey:  cm.ChainKey,
 		Public:    ps,
+		AllowList: allowList,
 	}
 	return nil
 }
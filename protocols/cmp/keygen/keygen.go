@@ -2,9 +2,11 @@ package keygen
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 
 	"github.com/taurusgroup/multi-party-sig/internal/round"
+	"github.com/taurusgroup/multi-party-sig/pkg/allowlist"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/polynomial"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/sample"
@@ -16,7 +18,36 @@ import (
 
 const Rounds round.Number = 5
 
+// MembershipRounds is the number of rounds of a refresh which also updates the allow list,
+// see StartMembership.
+const MembershipRounds round.Number = 6
+
 func Start(info round.Info, pl *pool.Pool, c *config.Config) protocol.StartFunc {
+	return start(info, pl, c, nil)
+}
+
+// StartMembership refreshes c like Start, and also replaces its allow list by one holding exactly members,
+// as returned by c.AllowList.Update. Since the new filter is computed locally, the parties check in an
+// additional round that they all obtained the same one, by broadcasting its digest.
+//
+// info.FinalRoundNumber must be MembershipRounds.
+func StartMembership(info round.Info, pl *pool.Pool, c *config.Config, members []party.ID) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		if c == nil {
+			return nil, errors.New("keygen: membership update without a config")
+		}
+		if info.FinalRoundNumber != MembershipRounds {
+			return nil, fmt.Errorf("keygen: membership update must have %d rounds", MembershipRounds)
+		}
+		allowList, err := c.AllowList.Update(c.PartyIDs(), members)
+		if err != nil {
+			return nil, fmt.Errorf("keygen: %w", err)
+		}
+		return start(info, pl, c, allowList)(sessionID)
+	}
+}
+
+func start(info round.Info, pl *pool.Pool, c *config.Config, allowList *allowlist.AllowList) protocol.StartFunc {
 	return func(sessionID []byte) (_ round.Session, err error) {
 		var helper *round.Helper
 		if c == nil {
@@ -40,6 +71,8 @@ func Start(info round.Info, pl *pool.Pool, c *config.Config) protocol.StartFunc
 				PreviousSecretECDSA:       c.ECDSA,
 				PreviousPublicSharesECDSA: PublicSharesECDSA,
 				PreviousChainKey:          c.ChainKey,
+				AllowList:                 c.AllowList,
+				UpdatedAllowList:          allowList,
 				VSSSecret:                 polynomial.NewPolynomial(group, helper.Threshold(), group.NewScalar()), // fᵢ(X) deg(fᵢ) = t, fᵢ(0) = 0
 			}, nil
 		}
@@ -7,8 +7,10 @@ import (
 	"github.com/fxamacker/cbor/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/taurusgroup/multi-party-sig/filters"
 	"github.com/taurusgroup/multi-party-sig/internal/round"
 	"github.com/taurusgroup/multi-party-sig/internal/test"
+	"github.com/taurusgroup/multi-party-sig/pkg/allowlist"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
 	"github.com/taurusgroup/multi-party-sig/pkg/pool"
 	"github.com/taurusgroup/multi-party-sig/protocols/cmp/config"
@@ -119,3 +121,60 @@ func TestRefresh(t *testing.T) {
 	}
 	checkOutput(t, rounds)
 }
+
+func TestRefreshMembers(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+
+	N := 4
+	T := N - 2
+	configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+	for _, c := range configs {
+		allowList, err := allowlist.NewAllowList("cuckoo", partyIDs, 0.0001)
+		require.NoError(t, err)
+		c.AllowList = allowList
+	}
+	removed := partyIDs[1]
+	members := partyIDs.Remove(removed)
+
+	rounds := make([]round.Session, 0, N)
+	for _, c := range configs {
+		info := round.Info{
+			ProtocolID:       "cmp/refresh-members-test",
+			FinalRoundNumber: MembershipRounds,
+			SelfID:           c.ID,
+			PartyIDs:         c.PartyIDs(),
+			Threshold:        T,
+			Group:            group,
+		}
+		r, err := StartMembership(info, pl, c, members)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+
+	for {
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+	checkOutput(t, rounds)
+
+	var digest []byte
+	for _, r := range rounds {
+		c := r.(*round.Output).Result.(*config.Config)
+		require.NotNil(t, c.AllowList, "refreshed config should have an allow list")
+		d, err := filters.Digest(c.AllowList.Filter)
+		require.NoError(t, err)
+		if digest == nil {
+			digest = d
+		}
+		assert.Equal(t, digest, d, "allow lists are different")
+		assert.NoError(t, c.CheckAllowList(members))
+		assert.False(t, c.AllowList.Allows(removed), "removed party should not be allowed")
+		assert.Equal(t, 0.0001, c.AllowList.E)
+	}
+
+	assert.True(t, configs[partyIDs[0]].AllowList.Allows(removed), "previous allow list should not be modified")
+}
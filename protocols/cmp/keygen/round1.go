@@ -6,6 +6,7 @@ import (
 
 	"github.com/taurusgroup/multi-party-sig/internal/round"
 	"github.com/taurusgroup/multi-party-sig/internal/types"
+	"github.com/taurusgroup/multi-party-sig/pkg/allowlist"
 	"github.com/taurusgroup/multi-party-sig/pkg/hash"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/polynomial"
@@ -37,6 +38,14 @@ type round1 struct {
 	// In that case, we will simply use the previous chain key at the very end.
 	PreviousChainKey types.RID
 
+	// AllowList is the allow list of the config being refreshed, which is kept as is
+	// unless UpdatedAllowList is set.
+	AllowList *allowlist.AllowList
+
+	// UpdatedAllowList replaces AllowList in a membership update,
+	// after all parties have checked they computed the same one in round 6.
+	UpdatedAllowList *allowlist.AllowList
+
 	// VSSSecret = fᵢ(X)
 	// Polynomial from which the new secret shares are computed.
 	// Keygen:  fᵢ(0) = xⁱ
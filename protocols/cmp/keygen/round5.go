@@ -3,6 +3,7 @@ package keygen
 import (
 	"errors"
 
+	"github.com/taurusgroup/multi-party-sig/filters"
 	"github.com/taurusgroup/multi-party-sig/internal/round"
 	sch "github.com/taurusgroup/multi-party-sig/pkg/zk/sch"
 	"github.com/taurusgroup/multi-party-sig/protocols/cmp/config"
@@ -50,8 +51,25 @@ func (round5) VerifyMessage(round.Message) error { return nil }
 func (r *round5) StoreMessage(round.Message) error { return nil }
 
 // Finalize implements round.Round.
-func (r *round5) Finalize(chan<- *round.Message) (round.Session, error) {
-	return r.ResultRound(r.UpdatedConfig), nil
+//
+// In a membership update, broadcast the digest of the updated allow list.
+func (r *round5) Finalize(out chan<- *round.Message) (round.Session, error) {
+	if r.UpdatedAllowList == nil {
+		r.UpdatedConfig.AllowList = r.AllowList
+		return r.ResultRound(r.UpdatedConfig), nil
+	}
+
+	digest, err := filters.Digest(r.UpdatedAllowList.Filter)
+	if err != nil {
+		return r, err
+	}
+	if err = r.BroadcastMessage(out, &broadcast6{AllowListDigest: digest}); err != nil {
+		return r, err
+	}
+	return &round6{
+		round5:          r,
+		AllowListDigest: digest,
+	}, nil
 }
 
 // MessageContent implements round.Round.
@@ -0,0 +1,67 @@
+package keygen
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/taurusgroup/multi-party-sig/internal/round"
+)
+
+var _ round.Round = (*round6)(nil)
+
+type round6 struct {
+	*round5
+	// AllowListDigest = filters.Digest of UpdatedAllowList
+	AllowListDigest []byte
+}
+
+type broadcast6 struct {
+	round.ReliableBroadcastContent
+	// AllowListDigest is the digest of the updated allow list computed by the sender
+	AllowListDigest []byte
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+//
+// - check that the updated allow list of the sender is the same as ours.
+func (r *round6) StoreBroadcastMessage(msg round.Message) error {
+	body, ok := msg.Content.(*broadcast6)
+	if !ok || body == nil {
+		return round.ErrInvalidContent
+	}
+
+	if len(body.AllowListDigest) == 0 {
+		return round.ErrNilFields
+	}
+
+	if !bytes.Equal(body.AllowListDigest, r.AllowListDigest) {
+		return errors.New("updated allow list differs")
+	}
+	return nil
+}
+
+// VerifyMessage implements round.Round.
+func (round6) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (r *round6) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round.
+//
+// - output the refreshed config along with the updated allow list.
+func (r *round6) Finalize(chan<- *round.Message) (round.Session, error) {
+	r.UpdatedConfig.AllowList = r.UpdatedAllowList
+	return r.ResultRound(r.UpdatedConfig), nil
+}
+
+// MessageContent implements round.Round.
+func (r *round6) MessageContent() round.Content { return nil }
+
+// RoundNumber implements round.Content.
+func (broadcast6) RoundNumber() round.Number { return 6 }
+
+// BroadcastContent implements round.BroadcastRound.
+func (r *round6) BroadcastContent() round.BroadcastContent { return &broadcast6{} }
+
+// Number implements round.Round.
+func (round6) Number() round.Number { return 6 }
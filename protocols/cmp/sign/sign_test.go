@@ -1,6 +1,7 @@
 package sign
 
 import (
+	"io"
 	mrand "math/rand"
 	"testing"
 
@@ -8,9 +9,12 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/taurusgroup/multi-party-sig/internal/round"
 	"github.com/taurusgroup/multi-party-sig/internal/test"
+	"github.com/taurusgroup/multi-party-sig/pkg/allowlist"
 	"github.com/taurusgroup/multi-party-sig/pkg/ecdsa"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
 	"github.com/taurusgroup/multi-party-sig/pkg/pool"
+	"github.com/taurusgroup/multi-party-sig/protocols/cmp/config"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -57,3 +61,60 @@ func TestRound(t *testing.T) {
 		assert.True(t, signature.Verify(publicPoint, messageHash), "expected valid signature")
 	}
 }
+
+func TestAllowList(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+
+	N := 4
+	T := N - 2
+
+	configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+	allowList, err := allowlist.NewAllowList("cuckoo", partyIDs, 0.0001)
+	require.NoError(t, err)
+	for _, c := range configs {
+		c.AllowList = allowList
+	}
+
+	messageHash := make([]byte, 64)
+	signers := partyIDs[:T+1]
+	c := configs[signers[0]]
+	assert.True(t, c.CanSign(signers))
+
+	data, err := c.MarshalBinary()
+	require.NoError(t, err)
+	decoded := config.EmptyConfig(group)
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	require.NotNil(t, decoded.AllowList)
+	assert.Equal(t, "cuckoo", decoded.AllowList.Name)
+	assert.True(t, decoded.CanSign(signers))
+
+	require.NoError(t, allowList.Suspend(signers[1]))
+	assert.False(t, c.CanSign(signers), "a suspended party should not be able to sign")
+	_, err = StartSign(c, signers, messageHash, pl)(nil)
+	assert.Error(t, err, "signing with a suspended party should fail")
+	others := []party.ID{signers[0], signers[2], partyIDs[3]}
+	assert.True(t, c.CanSign(others), "the other parties should still be able to sign")
+
+	require.NoError(t, allowList.Reinstate(signers[1]))
+	assert.True(t, c.CanSign(signers))
+	_, err = StartSign(c, signers, messageHash, pl)(nil)
+	assert.NoError(t, err)
+
+	derived, err := c.DeriveBIP32(0)
+	require.NoError(t, err)
+	require.NoError(t, derived.AllowList.Suspend(signers[1]))
+	assert.False(t, derived.CanSign(signers), "a suspended party should not be able to sign")
+	assert.True(t, c.CanSign(signers), "suspending a party in a derived config should not affect the parent")
+
+	empty := *c
+	empty.AllowList = &allowlist.AllowList{Name: "cuckoo"}
+	assert.False(t, empty.CanSign(signers), "an allow list without a filter should not allow anyone")
+	_, err = empty.MarshalBinary()
+	assert.ErrorIs(t, err, allowlist.ErrNoFilter)
+	_, err = empty.WriteTo(io.Discard)
+	assert.ErrorIs(t, err, allowlist.ErrNoFilter)
+	_, err = StartSign(&empty, signers, messageHash, pl)(nil)
+	assert.Error(t, err, "signing with an allow list without a filter should fail")
+}
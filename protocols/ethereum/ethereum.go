@@ -0,0 +1,136 @@
+// Package ethereum signs Ethereum transactions with a cmp.Config, without any RPC node.
+//
+// A Transaction is built from the fields of a legacy (EIP-155), access list (EIP-2930)
+// or dynamic fee (EIP-1559) transaction, and its Hash is what the parties sign, either with
+// Sign or PresignOnline. The resulting signature is then given to Assemble, which encodes
+// the signed transaction with the right v for its type, and checks that its sender is
+// the address of the shared public key.
+//
+// Fields which would normally be queried from a node, such as the nonce, gas limit and fees,
+// must be filled in by the caller.
+package ethereum
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/taurusgroup/multi-party-sig/pkg/address"
+	"github.com/taurusgroup/multi-party-sig/pkg/ecdsa"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+	"github.com/taurusgroup/multi-party-sig/pkg/pool"
+	"github.com/taurusgroup/multi-party-sig/pkg/protocol"
+	"github.com/taurusgroup/multi-party-sig/protocols/cmp"
+)
+
+// Transaction is an unsigned Ethereum transaction, along with the signer for its chain.
+type Transaction struct {
+	tx     *types.Transaction
+	signer types.Signer
+}
+
+// NewLegacy returns a legacy transaction for chainID, replay protected by EIP-155.
+func NewLegacy(chainID *big.Int, data *types.LegacyTx) (*Transaction, error) {
+	return newTransaction(chainID, data)
+}
+
+// NewAccessList returns an EIP-2930 transaction, for the chain given by data.ChainID.
+func NewAccessList(data *types.AccessListTx) (*Transaction, error) {
+	return newTransaction(data.ChainID, data)
+}
+
+// NewDynamicFee returns an EIP-1559 transaction, for the chain given by data.ChainID.
+func NewDynamicFee(data *types.DynamicFeeTx) (*Transaction, error) {
+	return newTransaction(data.ChainID, data)
+}
+
+func newTransaction(chainID *big.Int, data types.TxData) (*Transaction, error) {
+	if chainID == nil || chainID.Sign() <= 0 {
+		return nil, errors.New("ethereum: chain ID must be positive")
+	}
+	// NewTx copies data, so later changes by the caller don't affect the hash
+	return &Transaction{
+		tx:     types.NewTx(data),
+		signer: types.NewLondonSigner(chainID),
+	}, nil
+}
+
+// Unsigned returns the underlying unsigned transaction.
+func (t *Transaction) Unsigned() *types.Transaction {
+	return t.tx
+}
+
+// Hash returns the hash signed by the parties, which includes the chain ID.
+func (t *Transaction) Hash() []byte {
+	return t.signer.Hash(t.tx).Bytes()
+}
+
+// Sign is like cmp.Sign, for the hash of the transaction.
+// Returns *ecdsa.Signature if successful, which should be given to Assemble.
+func (t *Transaction) Sign(config *cmp.Config, signers []party.ID, pl *pool.Pool) protocol.StartFunc {
+	return cmp.Sign(config, signers, t.Hash(), pl)
+}
+
+// PresignOnline is like cmp.PresignOnline, for the hash of the transaction.
+// Returns *ecdsa.Signature if successful, which should be given to Assemble.
+func (t *Transaction) PresignOnline(config *cmp.Config, preSignature *ecdsa.PreSignature, pl *pool.Pool) protocol.StartFunc {
+	return cmp.PresignOnline(config, preSignature, t.Hash(), pl)
+}
+
+// Assemble returns the transaction signed with sig, after checking that its sender
+// is the address of public. sig is not modified.
+//
+// The MarshalBinary method of the result gives the raw transaction, ready to be broadcast.
+func (t *Transaction) Assemble(sig *ecdsa.Signature, public curve.Point) (*types.Transaction, error) {
+	if sig == nil || sig.R == nil || sig.S == nil {
+		return nil, errors.New("ethereum: nil signature")
+	}
+	if !sig.Verify(public, t.Hash()) {
+		return nil, errors.New("ethereum: invalid signature")
+	}
+	expected, err := address.Ethereum(public)
+	if err != nil {
+		return nil, fmt.Errorf("ethereum: %w", err)
+	}
+
+	rsv, err := sig.SigEthereum()
+	if err != nil {
+		return nil, fmt.Errorf("ethereum: %w", err)
+	}
+	// the signer encodes v as required by the transaction type
+	signed, err := t.tx.WithSignature(t.signer, rsv)
+	if err != nil {
+		return nil, fmt.Errorf("ethereum: %w", err)
+	}
+	if err = CheckSender(signed, expected); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+// Sender recovers the sender of a signed transaction.
+func Sender(signed *types.Transaction) (common.Address, error) {
+	if signed.ChainId().Sign() <= 0 {
+		return common.Address{}, errors.New("ethereum: transaction is not replay protected")
+	}
+	from, err := types.Sender(types.NewLondonSigner(signed.ChainId()), signed)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("ethereum: %w", err)
+	}
+	return from, nil
+}
+
+// CheckSender returns an error if the sender recovered from signed is not expected.
+func CheckSender(signed *types.Transaction, expected common.Address) error {
+	from, err := Sender(signed)
+	if err != nil {
+		return err
+	}
+	if from != expected {
+		return fmt.Errorf("ethereum: recovered sender %s, expected %s", from.Hex(), expected.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package ethereum_test
+
+import (
+	"crypto/rand"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taurusgroup/multi-party-sig/internal/test"
+	"github.com/taurusgroup/multi-party-sig/pkg/address"
+	"github.com/taurusgroup/multi-party-sig/pkg/ecdsa"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+	"github.com/taurusgroup/multi-party-sig/pkg/pool"
+	"github.com/taurusgroup/multi-party-sig/pkg/protocol"
+	"github.com/taurusgroup/multi-party-sig/protocols/cmp"
+	"github.com/taurusgroup/multi-party-sig/protocols/ethereum"
+)
+
+// run executes the protocol created by start for every party, and returns their results.
+func run(t *testing.T, ids party.IDSlice, start func(id party.ID) protocol.StartFunc) map[party.ID]interface{} {
+	net := test.NewNetwork(ids)
+	results := make(map[party.ID]interface{}, len(ids))
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id party.ID) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(start(id), nil)
+			require.NoError(t, err)
+			test.HandlerLoop(id, h, net)
+			r, err := h.Result()
+			require.NoError(t, err)
+			mtx.Lock()
+			results[id] = r
+			mtx.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return results
+}
+
+func TestSign(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+
+	configs, ids := test.GenerateConfig(curve.Secp256k1{}, 3, 1, rand.Reader, pl)
+	public := configs[ids[0]].PublicPoint()
+	from, err := address.Ethereum(public)
+	require.NoError(t, err)
+
+	chainID := big.NewInt(11155111)
+	to := common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	accessList := types.AccessList{{Address: to, StorageKeys: []common.Hash{{1}}}}
+
+	legacy, err := ethereum.NewLegacy(chainID, &types.LegacyTx{
+		Nonce:    0,
+		GasPrice: big.NewInt(20_000_000_000),
+		Gas:      21_000,
+		To:       &to,
+		Value:    big.NewInt(1),
+	})
+	require.NoError(t, err)
+	accessListTx, err := ethereum.NewAccessList(&types.AccessListTx{
+		ChainID:    chainID,
+		Nonce:      1,
+		GasPrice:   big.NewInt(20_000_000_000),
+		Gas:        30_000,
+		To:         &to,
+		Value:      big.NewInt(2),
+		AccessList: accessList,
+	})
+	require.NoError(t, err)
+	dynamicFee, err := ethereum.NewDynamicFee(&types.DynamicFeeTx{
+		ChainID:    chainID,
+		Nonce:      2,
+		GasTipCap:  big.NewInt(1_000_000_000),
+		GasFeeCap:  big.NewInt(30_000_000_000),
+		Gas:        30_000,
+		To:         &to,
+		Value:      big.NewInt(3),
+		Data:       []byte("hello"),
+		AccessList: accessList,
+	})
+	require.NoError(t, err)
+
+	check := func(tx *ethereum.Transaction, sig *ecdsa.Signature, txType uint8) {
+		signed, err := tx.Assemble(sig, public)
+		require.NoError(t, err)
+		assert.Equal(t, txType, signed.Type())
+		assert.Equal(t, chainID, signed.ChainId())
+		assert.True(t, sig.Verify(public, tx.Hash()), "signature should not be modified")
+
+		// decode the raw transaction, as a node would
+		raw, err := signed.MarshalBinary()
+		require.NoError(t, err)
+		decoded := new(types.Transaction)
+		require.NoError(t, decoded.UnmarshalBinary(raw))
+		assert.Equal(t, signed.Hash(), decoded.Hash())
+		sender, err := ethereum.Sender(decoded)
+		require.NoError(t, err)
+		assert.Equal(t, from, sender)
+
+		if txType == types.LegacyTxType {
+			v, _, _ := decoded.RawSignatureValues()
+			base := new(big.Int).Add(new(big.Int).Lsh(chainID, 1), big.NewInt(35))
+			assert.True(t, v.Cmp(base) == 0 || v.Cmp(new(big.Int).Add(base, big.NewInt(1))) == 0, "v should follow EIP-155")
+		}
+
+		other, err := address.Ethereum(curve.Secp256k1{}.NewBasePoint())
+		require.NoError(t, err)
+		assert.Error(t, ethereum.CheckSender(decoded, other))
+		_, err = tx.Assemble(sig, curve.Secp256k1{}.NewBasePoint())
+		assert.Error(t, err, "signature should not be accepted for another key")
+	}
+
+	signers := ids[:2]
+	for txType, tx := range map[uint8]*ethereum.Transaction{
+		types.LegacyTxType:     legacy,
+		types.AccessListTxType: accessListTx,
+	} {
+		results := run(t, signers, func(id party.ID) protocol.StartFunc {
+			return tx.Sign(configs[id], signers, pl)
+		})
+		for _, r := range results {
+			require.IsType(t, &ecdsa.Signature{}, r)
+			check(tx, r.(*ecdsa.Signature), txType)
+		}
+	}
+
+	preSignatures := run(t, signers, func(id party.ID) protocol.StartFunc {
+		return cmp.Presign(configs[id], signers, pl)
+	})
+	results := run(t, signers, func(id party.ID) protocol.StartFunc {
+		return dynamicFee.PresignOnline(configs[id], preSignatures[id].(*ecdsa.PreSignature), pl)
+	})
+	for _, r := range results {
+		require.IsType(t, &ecdsa.Signature{}, r)
+		check(dynamicFee, r.(*ecdsa.Signature), types.DynamicFeeTxType)
+	}
+
+	_, err = ethereum.NewLegacy(nil, &types.LegacyTx{To: &to})
+	assert.Error(t, err)
+	_, err = ethereum.NewDynamicFee(&types.DynamicFeeTx{To: &to})
+	assert.Error(t, err)
+}
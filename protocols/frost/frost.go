@@ -2,6 +2,7 @@ package frost
 
 import (
 	"github.com/taurusgroup/multi-party-sig/internal/round"
+	"github.com/taurusgroup/multi-party-sig/pkg/allowlist"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
 	"github.com/taurusgroup/multi-party-sig/pkg/party"
 	"github.com/taurusgroup/multi-party-sig/pkg/protocol"
@@ -10,9 +11,12 @@ import (
 )
 
 type (
-	Config        = keygen.Config
-	TaprootConfig = keygen.TaprootConfig
-	Signature     = sign.Signature
+	Config                  = keygen.Config
+	TaprootConfig           = keygen.TaprootConfig
+	MembershipConfig        = keygen.MembershipConfig
+	TaprootMembershipConfig = keygen.TaprootMembershipConfig
+	AllowList               = allowlist.AllowList
+	Signature               = sign.Signature
 )
 
 // EmptyConfig creates an empty Config with a specific group.
@@ -81,6 +85,34 @@ func RefreshTaproot(config *TaprootConfig, participants []party.ID) protocol.Sta
 	return keygen.StartKeygenCommon(true, curve.Secp256k1{}, participants, config.Threshold, config.ID, config.PrivateShare, publicKey, verificationShares)
 }
 
+// RefreshMembers is like Refresh, but also replaces allowList by one holding exactly members,
+// which must be among participants. Parties which are no longer members are deleted from the filter
+// if it supports it, otherwise a new filter is built, see allowlist.AllowList.Update.
+// allowList may be nil, in which case a new one is built.
+//
+// All parties must use the same allow list and members. They check that they obtained the same filter
+// by broadcasting its digest in an additional round.
+//
+// This will return MembershipConfig instead of Config, at the end of the protocol.
+func RefreshMembers(config *Config, participants []party.ID, allowList *AllowList, members []party.ID) protocol.StartFunc {
+	return keygen.StartMembership(false, config.Curve(), participants, config.Threshold, config.ID, config.PrivateShare, config.PublicKey, config.VerificationShares.Points, allowList, members)
+}
+
+// RefreshMembersTaproot is like RefreshMembers, but will make Taproot / BIP-340 compatible keys.
+//
+// This will return TaprootMembershipConfig instead of MembershipConfig, at the end of the protocol.
+func RefreshMembersTaproot(config *TaprootConfig, participants []party.ID, allowList *AllowList, members []party.ID) protocol.StartFunc {
+	publicKey, err := curve.Secp256k1{}.LiftX(config.PublicKey)
+	if err != nil {
+		return protocol.Failed(err)
+	}
+	verificationShares := make(map[party.ID]curve.Point, len(config.VerificationShares))
+	for k, v := range config.VerificationShares {
+		verificationShares[k] = v
+	}
+	return keygen.StartMembership(true, curve.Secp256k1{}, participants, config.Threshold, config.ID, config.PrivateShare, publicKey, verificationShares, allowList, members)
+}
+
 // Sign initiates the protocol for producing a threshold signature, with Frost.
 //
 // result is the result of the key generation phase, for this participant.
@@ -75,6 +75,22 @@ func do(t *testing.T, id party.ID, ids []party.ID, threshold int, message []byte
 	require.IsType(t, taproot.Signature{}, signResult)
 	taprootSignature := signResult.(taproot.Signature)
 	assert.True(t, cTaproot.PublicKey.Verify(taprootSignature, message))
+
+	// sign for the BIP-86 output key of the taproot key
+	tweaked, err := cTaproot.Tweak(nil)
+	require.NoError(t, err)
+	_, outputKey, err := cTaproot.PublicKey.Tweak(nil)
+	require.NoError(t, err)
+	require.Equal(t, outputKey, tweaked.PublicKey)
+
+	h, err = protocol.NewMultiHandler(SignTaproot(tweaked, ids, message), nil)
+	require.NoError(t, err)
+
+	test.HandlerLoop(c.ID, h, n)
+
+	signResult, err = h.Result()
+	require.NoError(t, err)
+	assert.True(t, outputKey.Verify(signResult.(taproot.Signature), message))
 }
 
 func TestFrost(t *testing.T) {
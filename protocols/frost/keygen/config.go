@@ -6,6 +6,7 @@ import (
 
 	"github.com/taurusgroup/multi-party-sig/internal/bip32"
 	"github.com/taurusgroup/multi-party-sig/internal/params"
+	"github.com/taurusgroup/multi-party-sig/pkg/allowlist"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
 	"github.com/taurusgroup/multi-party-sig/pkg/party"
 	"github.com/taurusgroup/multi-party-sig/pkg/taproot"
@@ -101,6 +102,20 @@ func (r *Config) DeriveChild(i uint32) (*Config, error) {
 	return r.Derive(scalar, newChainKey)
 }
 
+// MembershipConfig is the result of a refresh which also updates an allow list, see StartMembership.
+type MembershipConfig struct {
+	*Config
+	// AllowList holds exactly the members passed to StartMembership.
+	AllowList *allowlist.AllowList
+}
+
+// TaprootMembershipConfig is like MembershipConfig, but for Taproot / BIP-340 keys.
+type TaprootMembershipConfig struct {
+	*TaprootConfig
+	// AllowList holds exactly the members passed to StartMembership.
+	AllowList *allowlist.AllowList
+}
+
 // TaprootConfig is like result, but for Taproot / BIP-340 keys.
 //
 // The main difference is that our public key is an actual taproot public key.
@@ -157,7 +172,23 @@ func (r *TaprootConfig) Derive(adjust *curve.Secp256k1Scalar, newChainKey []byte
 	if len(newChainKey) != params.SecBytes {
 		return nil, fmt.Errorf("expecte %d bytes for chain key, found %d", params.SecBytes, len(newChainKey))
 	}
+	return r.adjust(adjust, newChainKey)
+}
+
+// Tweak returns the config of the BIP-341 output key of this internal key, committing to merkleRoot,
+// which is empty for a BIP-86 key path only output, see taproot.PublicKey.Tweak.
+//
+// Signatures produced with the result are valid for the output key, and can spend the key path.
+func (r *TaprootConfig) Tweak(merkleRoot []byte) (*TaprootConfig, error) {
+	t, _, err := r.PublicKey.Tweak(merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	return r.adjust(t, r.ChainKey)
+}
 
+// adjust adds adjust to the secret key, as in Derive, without checking the chain key.
+func (r *TaprootConfig) adjust(adjust *curve.Secp256k1Scalar, newChainKey []byte) (*TaprootConfig, error) {
 	adjustG := adjust.ActOnBase()
 	verificationShares := make(map[party.ID]*curve.Secp256k1Point, len(r.VerificationShares))
 	for k, v := range r.VerificationShares {
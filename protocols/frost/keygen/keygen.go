@@ -1,9 +1,11 @@
 package keygen
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/taurusgroup/multi-party-sig/internal/round"
+	"github.com/taurusgroup/multi-party-sig/pkg/allowlist"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
 	"github.com/taurusgroup/multi-party-sig/pkg/party"
 	"github.com/taurusgroup/multi-party-sig/pkg/protocol"
@@ -13,8 +15,13 @@ const (
 	// Frost KeyGen with Threshold.
 	protocolID        = "frost/keygen-threshold"
 	protocolIDTaproot = "frost/keygen-threshold-taproot"
+	// Frost refresh which also updates an allow list.
+	protocolIDMembership        = "frost/refresh-members"
+	protocolIDMembershipTaproot = "frost/refresh-members-taproot"
 	// This protocol has 3 concrete rounds.
 	protocolRounds round.Number = 3
+	// A membership update has an additional round, to agree on the allow list.
+	protocolMembershipRounds round.Number = 4
 )
 
 // These assert that our rounds implement the round.Round interface.
@@ -22,9 +29,32 @@ var (
 	_ round.Round = (*round1)(nil)
 	_ round.Round = (*round2)(nil)
 	_ round.Round = (*round3)(nil)
+	_ round.Round = (*round4)(nil)
 )
 
 func StartKeygenCommon(taproot bool, group curve.Curve, participants []party.ID, threshold int, selfID party.ID, privateShare curve.Scalar, publicKey curve.Point, verificationShares map[party.ID]curve.Point) protocol.StartFunc {
+	return startKeygen(taproot, group, participants, threshold, selfID, privateShare, publicKey, verificationShares, nil)
+}
+
+// StartMembership refreshes a key like StartKeygenCommon, and also replaces allowList by one holding exactly members,
+// as returned by allowList.Update. Since the new filter is computed locally, the parties check in an
+// additional round that they all obtained the same one, by broadcasting its digest.
+//
+// The result is a *MembershipConfig, or a *TaprootMembershipConfig for taproot keys.
+func StartMembership(taproot bool, group curve.Curve, participants []party.ID, threshold int, selfID party.ID, privateShare curve.Scalar, publicKey curve.Point, verificationShares map[party.ID]curve.Point, allowList *allowlist.AllowList, members []party.ID) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		if privateShare == nil || publicKey == nil {
+			return nil, errors.New("keygen.StartMembership: membership update without a key to refresh")
+		}
+		updated, err := allowList.Update(participants, members)
+		if err != nil {
+			return nil, fmt.Errorf("keygen.StartMembership: %w", err)
+		}
+		return startKeygen(taproot, group, participants, threshold, selfID, privateShare, publicKey, verificationShares, updated)(sessionID)
+	}
+}
+
+func startKeygen(taproot bool, group curve.Curve, participants []party.ID, threshold int, selfID party.ID, privateShare curve.Scalar, publicKey curve.Point, verificationShares map[party.ID]curve.Point, allowList *allowlist.AllowList) protocol.StartFunc {
 	return func(sessionID []byte) (round.Session, error) {
 		info := round.Info{
 			FinalRoundNumber: protocolRounds,
@@ -33,9 +63,16 @@ func StartKeygenCommon(taproot bool, group curve.Curve, participants []party.ID,
 			Threshold:        threshold,
 			Group:            group,
 		}
-		if taproot {
+		switch {
+		case allowList != nil && taproot:
+			info.ProtocolID = protocolIDMembershipTaproot
+			info.FinalRoundNumber = protocolMembershipRounds
+		case allowList != nil:
+			info.ProtocolID = protocolIDMembership
+			info.FinalRoundNumber = protocolMembershipRounds
+		case taproot:
 			info.ProtocolID = protocolIDTaproot
-		} else {
+		default:
 			info.ProtocolID = protocolID
 		}
 
@@ -67,6 +104,7 @@ func StartKeygenCommon(taproot bool, group curve.Curve, participants []party.ID,
 			privateShare:       privateShare,
 			verificationShares: verificationSharesCopy,
 			publicKey:          publicKey,
+			allowList:          allowList,
 		}, nil
 	}
 }
@@ -6,8 +6,10 @@ import (
 	"github.com/fxamacker/cbor/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/taurusgroup/multi-party-sig/filters"
 	"github.com/taurusgroup/multi-party-sig/internal/round"
 	"github.com/taurusgroup/multi-party-sig/internal/test"
+	"github.com/taurusgroup/multi-party-sig/pkg/allowlist"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/polynomial"
 	"github.com/taurusgroup/multi-party-sig/pkg/party"
@@ -164,3 +166,93 @@ func TestKeygenTaproot(t *testing.T) {
 
 	checkOutputTaproot(t, rounds, partyIDs)
 }
+
+func TestRefreshMembers(t *testing.T) {
+	group := curve.Secp256k1{}
+	N := 5
+	T := N - 2
+	partyIDs := test.PartyIDs(N)
+	allowList, err := allowlist.NewAllowList("cuckoo", partyIDs, 0.0001)
+	require.NoError(t, err)
+	removed := partyIDs[1]
+	members := partyIDs.Remove(removed)
+
+	for _, taproot := range []bool{false, true} {
+		rounds := make([]round.Session, 0, N)
+		for _, partyID := range partyIDs {
+			r, err := StartKeygenCommon(taproot, group, partyIDs, T, partyID, nil, nil, nil)(nil)
+			require.NoError(t, err, "round creation should not result in an error")
+			rounds = append(rounds, r)
+		}
+		for {
+			err, done := test.Rounds(rounds, nil)
+			require.NoError(t, err, "failed to process round")
+			if done {
+				break
+			}
+		}
+
+		for i, r := range rounds {
+			var (
+				privateShare       curve.Scalar
+				publicKey          curve.Point
+				verificationShares = make(map[party.ID]curve.Point)
+			)
+			switch c := r.(*round.Output).Result.(type) {
+			case *Config:
+				privateShare, publicKey = c.PrivateShare, c.PublicKey
+				verificationShares = c.VerificationShares.Points
+			case *TaprootConfig:
+				privateShare = c.PrivateShare
+				publicKey, err = group.LiftX(c.PublicKey)
+				require.NoError(t, err)
+				for k, v := range c.VerificationShares {
+					verificationShares[k] = v
+				}
+			}
+			rounds[i], err = StartMembership(taproot, group, partyIDs, T, r.SelfID(), privateShare, publicKey, verificationShares, allowList, members)(nil)
+			require.NoError(t, err, "round creation should not result in an error")
+		}
+		for {
+			err, done := test.Rounds(rounds, nil)
+			require.NoError(t, err, "failed to process round")
+			if done {
+				break
+			}
+		}
+
+		var digest []byte
+		for i, r := range rounds {
+			output := r.(*round.Output)
+			var updated *allowlist.AllowList
+			if taproot {
+				result, ok := output.Result.(*TaprootMembershipConfig)
+				require.True(t, ok, "expected a taproot membership result")
+				updated, output.Result = result.AllowList, result.TaprootConfig
+			} else {
+				result, ok := output.Result.(*MembershipConfig)
+				require.True(t, ok, "expected a membership result")
+				updated, output.Result = result.AllowList, result.Config
+			}
+			rounds[i] = output
+
+			d, err := filters.Digest(updated.Filter)
+			require.NoError(t, err)
+			if digest == nil {
+				digest = d
+			}
+			assert.Equal(t, digest, d, "allow lists are different")
+			for _, id := range members {
+				assert.True(t, updated.Allows(id), "member should be allowed")
+			}
+			assert.False(t, updated.Allows(removed), "removed party should not be allowed")
+		}
+		if taproot {
+			checkOutputTaproot(t, rounds, partyIDs)
+		} else {
+			checkOutput(t, rounds, partyIDs)
+		}
+	}
+
+	assert.True(t, allowList.Allows(removed), "previous allow list should not be modified")
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/taurusgroup/multi-party-sig/internal/round"
 	"github.com/taurusgroup/multi-party-sig/internal/types"
+	"github.com/taurusgroup/multi-party-sig/pkg/allowlist"
 	"github.com/taurusgroup/multi-party-sig/pkg/hash"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
 	"github.com/taurusgroup/multi-party-sig/pkg/math/polynomial"
@@ -43,6 +44,10 @@ type round1 struct {
 	verificationShares map[party.ID]curve.Point
 	// publicKey should be the previous public key when refreshing, and 0 otherwise.
 	publicKey curve.Point
+	// allowList is the updated allow list in a membership update, and nil otherwise.
+	//
+	// The parties check that they computed the same one in round 4.
+	allowList *allowlist.AllowList
 }
 
 // VerifyMessage implements round.Round.
@@ -3,6 +3,7 @@ package keygen
 import (
 	"fmt"
 
+	"github.com/taurusgroup/multi-party-sig/filters"
 	"github.com/taurusgroup/multi-party-sig/internal/round"
 	"github.com/taurusgroup/multi-party-sig/internal/types"
 	"github.com/taurusgroup/multi-party-sig/pkg/hash"
@@ -96,7 +97,9 @@ func (r *round3) StoreMessage(msg round.Message) error {
 }
 
 // Finalize implements round.Round.
-func (r *round3) Finalize(chan<- *round.Message) (round.Session, error) {
+//
+// In a membership update, broadcast the digest of the updated allow list.
+func (r *round3) Finalize(out chan<- *round.Message) (round.Session, error) {
 	ChainKey := types.EmptyRID()
 	for _, j := range r.PartyIDs() {
 		ChainKey.XOR(r.ChainKeys[j])
@@ -157,22 +160,43 @@ func (r *round3) Finalize(chan<- *round.Message) (round.Session, error) {
 		for k, v := range r.verificationShares {
 			secpVerificationShares[k] = v.(*curve.Secp256k1Point)
 		}
-		return r.ResultRound(&TaprootConfig{
+		return r.result(out, &TaprootConfig{
 			ID:                 r.SelfID(),
 			Threshold:          r.threshold,
 			PrivateShare:       r.privateShare.(*curve.Secp256k1Scalar),
 			PublicKey:          YSecp.XBytes()[:],
 			VerificationShares: secpVerificationShares,
-		}), nil
+		})
 	}
 
-	return r.ResultRound(&Config{
+	return r.result(out, &Config{
 		ID:                 r.SelfID(),
 		Threshold:          r.threshold,
 		PrivateShare:       r.privateShare,
 		PublicKey:          r.publicKey,
 		VerificationShares: party.NewPointMap(r.verificationShares),
-	}), nil
+	})
+}
+
+// result outputs config, a *Config or *TaprootConfig, unless this is a membership update,
+// in which case it is kept until the parties agree on the updated allow list in round 4.
+func (r *round3) result(out chan<- *round.Message, config interface{}) (round.Session, error) {
+	if r.allowList == nil {
+		return r.ResultRound(config), nil
+	}
+
+	digest, err := filters.Digest(r.allowList.Filter)
+	if err != nil {
+		return r, err
+	}
+	if err = r.BroadcastMessage(out, &broadcast4{AllowListDigest: digest}); err != nil {
+		return r, err
+	}
+	return &round4{
+		round3:          r,
+		config:          config,
+		AllowListDigest: digest,
+	}, nil
 }
 
 // RoundNumber implements round.Content.
@@ -0,0 +1,71 @@
+package keygen
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/taurusgroup/multi-party-sig/internal/round"
+)
+
+// This round only happens in a membership update, where the parties check
+// that they computed the same allow list.
+type round4 struct {
+	*round3
+	// config is the refreshed *Config or *TaprootConfig.
+	config interface{}
+	// AllowListDigest = filters.Digest of allowList
+	AllowListDigest []byte
+}
+
+type broadcast4 struct {
+	round.ReliableBroadcastContent
+	// AllowListDigest is the digest of the updated allow list computed by the sender
+	AllowListDigest []byte
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+//
+// - check that the updated allow list of the sender is the same as ours.
+func (r *round4) StoreBroadcastMessage(msg round.Message) error {
+	body, ok := msg.Content.(*broadcast4)
+	if !ok || body == nil {
+		return round.ErrInvalidContent
+	}
+
+	if len(body.AllowListDigest) == 0 {
+		return round.ErrNilFields
+	}
+
+	if !bytes.Equal(body.AllowListDigest, r.AllowListDigest) {
+		return errors.New("updated allow list differs")
+	}
+	return nil
+}
+
+// VerifyMessage implements round.Round.
+func (round4) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (round4) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round.
+//
+// - output the refreshed config along with the updated allow list.
+func (r *round4) Finalize(chan<- *round.Message) (round.Session, error) {
+	if c, ok := r.config.(*TaprootConfig); ok {
+		return r.ResultRound(&TaprootMembershipConfig{TaprootConfig: c, AllowList: r.allowList}), nil
+	}
+	return r.ResultRound(&MembershipConfig{Config: r.config.(*Config), AllowList: r.allowList}), nil
+}
+
+// MessageContent implements round.Round.
+func (round4) MessageContent() round.Content { return nil }
+
+// RoundNumber implements round.Content.
+func (broadcast4) RoundNumber() round.Number { return 4 }
+
+// BroadcastContent implements round.BroadcastRound.
+func (r *round4) BroadcastContent() round.BroadcastContent { return &broadcast4{} }
+
+// Number implements round.Round.
+func (round4) Number() round.Number { return 4 }
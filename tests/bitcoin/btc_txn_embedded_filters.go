@@ -7,18 +7,22 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"github.com/bits-and-blooms/bloom/v3"
 	"github.com/btcsuite/btcd/btcec/v2"
-	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
-	"github.com/btcsuite/btcd/wire"
 	"github.com/joho/godotenv"
-	cuckoofilter "github.com/panmari/cuckoofilter"
+	"github.com/taurusgroup/multi-party-sig/filters"
+	_ "github.com/taurusgroup/multi-party-sig/filters/bloom"
+	_ "github.com/taurusgroup/multi-party-sig/filters/cuckoo"
+	"github.com/taurusgroup/multi-party-sig/filters/embed"
+	_ "github.com/taurusgroup/multi-party-sig/filters/gcs"
+	_ "github.com/taurusgroup/multi-party-sig/filters/xor"
+	"github.com/taurusgroup/multi-party-sig/pkg/taproot"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 )
 
 func main() {
@@ -41,22 +45,40 @@ func main() {
 	// Fetch the current fee rate from the mempool.space API to calculate the transaction fee
 	feeRate := getCurrentFeeRate()
 
-	// Define list of csv documents to be created:
-	type FilterFunc func(publicKeys []string, n uint, fp float64) []byte
+	// The key signing the transactions, which also signs the reveal of taproot envelopes
+	privKeyBytes, err := hex.DecodeString(os.Getenv("PRIVATE_KEY"))
+	if err != nil {
+		log.Fatalf("Failed to decode private key: %v", err)
+	}
+	publicKey, err := taproot.SecretKey(privKeyBytes).Public()
+	if err != nil {
+		log.Fatalf("Failed to compute the public key: %v", err)
+	}
 
-	type FuncFilePair struct {
-		Func FilterFunc
-		File string
+	// Define the embedding strategies to compare, from the standard OP_RETURN output
+	// to an envelope in the witness of a taproot script path spend, with the relay policy
+	// they need. The relaxed multi-push output is only relayed by nodes accepting larger
+	// OP_RETURN outputs, such as the -datacarriersize default of Bitcoin Core 30.
+	strategies := []struct {
+		policy   string
+		strategy embed.Strategy
+	}{
+		{"standard", embed.OpReturn{}},
+		{"standard", embed.MultiPush{}},
+		{"datacarriersize=100000", embed.MultiPush{MaxScriptSize: 100_000, PushSize: txscript.MaxScriptElementSize}},
+		{"standard", embed.TaprootEnvelope{Key: publicKey}},
 	}
 
-	funcFilePairs := []FuncFilePair{
-		{getBloomFilter, "BitcoinBloomResults.csv"},
-		{getCuckooFilter, "BitcoinCuckooResults.csv"},
+	// Define the filters to test, and the csv documents to be created.
+	// The filters are looked up by name in the filters registry, and can be
+	// selected with a comma separated FILTERS environment variable, e.g. FILTERS=bloom,cuckoo
+	filterNames := filters.Names()
+	if env := os.Getenv("FILTERS"); env != "" {
+		filterNames = strings.Split(env, ",")
 	}
 
-	for _, pair := range funcFilePairs {
-		fileName := pair.File
-		filterFunc := pair.Func
+	for _, filterName := range filterNames {
+		fileName := "Bitcoin" + strings.ToUpper(filterName[:1]) + filterName[1:] + "Results.csv"
 
 		// Create a new CSV file
 		file, err := os.Create(fileName)
@@ -70,7 +92,7 @@ func main() {
 		defer writer.Flush()
 
 		// Write the header row to the CSV file
-		err = writer.Write([]string{"partySet", "filterSize", "transactionSize", "transactionFeeBTC", "transactionFeeSatoshis"})
+		err = writer.Write([]string{"partySet", "strategy", "policy", "filterSize", "transactionSize", "transactionWeight", "transactionFeeBTC", "transactionFeeSatoshis"})
 		if err != nil {
 			log.Fatalf("Failed to write to CSV file: %v", err)
 		}
@@ -85,87 +107,56 @@ func main() {
 
 			// Generate a Cuckoo filter for the private keys
 			// Generating a filter for total accepted items for a set. E.g. filter of 4 which would have 3 items
-			serializedFilterBytes := filterFunc(publicKeys, uint(partySet[0]), 0.0001)
+			serializedFilterBytes := getFilter(filterName, publicKeys, uint(partySet[0]), 0.0001)
 
 			// Convert the serializedCuckooFilter to []byte if it's not already in that format
 			//serializedFilterBytes := []byte(serializedCuckooFilter)
 			println("The size of the serializedFilterBytes is: ", len(serializedFilterBytes), " bytes")
 
-			// Create a new script builder
-			builder := txscript.NewScriptBuilder()
-
-			// Add the serializedCuckooFilter to the script
-			builder.AddData(serializedFilterBytes)
-
-			// Get the final script
-			scriptSig, err := builder.Script()
-			if err != nil {
-				log.Fatalf("Failed to create script: %v", err)
+			// Compare with the smallest filter meeting the same rate within the OP_RETURN limit
+			if plan, err := filters.PlanBudget(uint(partySet[0]), 0.0001, filters.OpReturnBudget); err == nil {
+				fmt.Println("Planned filter for the OP_RETURN limit:", plan)
 			}
 
-			println("The size of the scriptSig is: ", len(scriptSig), " bytes")
-
-			// Create a new message transaction
-			msgTx := wire.NewMsgTx(wire.TxVersion)
-
-			// Create a new transaction using the serializedFilterBytes as a ScriptPubKey
-			// Sending 0.0001 BTC to the address, which equals 10,000 satoshis
-			txOut := wire.NewTxOut(10000, scriptSig)
-
-			// Add the output to the transaction
-			msgTx.AddTxOut(txOut)
-
-			// Format the addresses to send the transaction from and to
-			privKeyBytes, err := hex.DecodeString(os.Getenv("PRIVATE_KEY"))
-			if err != nil {
-				log.Fatalf("Failed to decode private key: %v", err)
-			}
-			privateKey, _ := btcec.PrivKeyFromBytes(privKeyBytes)
-
-			// Create a new transaction input
-			prevTxID := wire.NewOutPoint(&chainhash.Hash{}, ^uint32(0)) // replace with your previous transaction ID and index
-			txIn := wire.NewTxIn(prevTxID, nil, nil)
-
-			// Add the input to the transaction
-			msgTx.AddTxIn(txIn)
-
-			// Sign the transaction
-			signature, err := txscript.SignatureScript(msgTx, 0, scriptSig, txscript.SigHashAll, privateKey, false)
-			if err != nil {
-				log.Fatalf("Failed to sign transaction: %v", err)
-			}
-
-			// set the SignatureScript on the transaction input.
-			msgTx.TxIn[0].SignatureScript = signature
-
-			// Measure the transaction size
-			txSize := getTxSize(msgTx)
-			println("The transaction size is: ", txSize, " bytes")
-
-			// Measure the transaction fee
-			txFeeSatoshis := txSize * feeRate
-			println("The transaction fee is: ", txFeeSatoshis, " satoshis")
-
-			//txOutValue := msgTx.TxOut[0].Value
-			//println("The transaction output value is: ", txOutValue, " satoshis")
-
-			// Calculate the transaction fee
-			//txOutValueInBTC := float64(txOutValue) / 100000000
-			//println("The transaction fee is: ", txOutValueInBTC, " BTC")
-
-			txFeeInBTC := float64(txFeeSatoshis) / 100000000
-			println("The transaction fee is: ", txFeeInBTC, " BTC")
-
-			// Write the results to the CSV file
-			err = writer.Write([]string{
-				fmt.Sprintf("%d of %d", partySet[0], partySet[1]),
-				strconv.Itoa(len(serializedFilterBytes)),
-				strconv.Itoa(txSize),
-				fmt.Sprintf("%.8f", txFeeInBTC),
-				strconv.Itoa(int(txFeeSatoshis)),
-			})
-			if err != nil {
-				log.Fatalf("Failed to write to CSV file: %v", err)
+			// Measure every embedding strategy, skipping those the filter doesn't fit in.
+			// The transactions spend a taproot key path input, and the envelope is signed by the same key.
+			for _, s := range strategies {
+				strategy := s.strategy
+				txs, err := strategy.Template(serializedFilterBytes)
+				if err != nil {
+					fmt.Printf("Skipping %s: %v\n", strategy.Name(), err)
+					continue
+				}
+				extracted, err := strategy.Extract(txs[len(txs)-1])
+				if err != nil || !bytes.Equal(extracted, serializedFilterBytes) {
+					log.Fatalf("Failed to extract the filter with %s: %v", strategy.Name(), err)
+				}
+
+				// Measure the transaction size, in virtual bytes, which is what fees are paid for
+				size := embed.Measure(txs...)
+				println("The transaction size is: ", size.VSize, " vbytes, ", size.Weight, " weight units")
+
+				// Measure the transaction fee
+				txFeeSatoshis := size.VSize * int64(feeRate)
+				println("The transaction fee is: ", txFeeSatoshis, " satoshis")
+
+				txFeeInBTC := float64(txFeeSatoshis) / 100000000
+				println("The transaction fee is: ", txFeeInBTC, " BTC")
+
+				// Write the results to the CSV file
+				err = writer.Write([]string{
+					fmt.Sprintf("%d of %d", partySet[0], partySet[1]),
+					strategy.Name(),
+					s.policy,
+					strconv.Itoa(len(serializedFilterBytes)),
+					strconv.FormatInt(size.VSize, 10),
+					strconv.FormatInt(size.Weight, 10),
+					fmt.Sprintf("%.8f", txFeeInBTC),
+					strconv.FormatInt(txFeeSatoshis, 10),
+				})
+				if err != nil {
+					log.Fatalf("Failed to write to CSV file: %v", err)
+				}
 			}
 		}
 
@@ -201,7 +192,7 @@ func generatePublicKeys(privateKeys []string) []string {
 	return publicKeys
 }
 
-func getBloomFilter(publicKeys []string, n uint, fp float64) []byte {
+func getFilter(name string, publicKeys []string, n uint, fp float64) []byte {
 
 	if n == 0 {
 		n = 3 // default value
@@ -209,64 +200,30 @@ func getBloomFilter(publicKeys []string, n uint, fp float64) []byte {
 	if fp == 0 {
 		fp = 0.01 // default value
 	}
-	//Create a new Bloom Filter with 1,000,000 items and 0.001% false positive rate
-	bf := bloom.NewWithEstimates(n, fp)
-
-	for _, pubKey := range publicKeys {
-		pubKeyBytes, _ := hex.DecodeString(pubKey)
-		bf.Add(pubKeyBytes)
 
-		if bf.Test(pubKeyBytes) {
-			fmt.Println(pubKeyBytes, " Exists!")
-		}
-	}
-
-	// Serialize the Bloom filter
-	// Assuming `b` is your bloom filter
-	var buf bytes.Buffer
-	_, err := bf.WriteTo(&buf)
-	if err != nil {
-		log.Fatalf("Error serializing the Bloom filter: %v", err)
-	}
-
-	serializedBloomFilter := buf.Bytes()
-
-	return serializedBloomFilter
-}
-
-func getTxSize(msgTx *wire.MsgTx) int {
-	// Serialize the transaction
-	var buf bytes.Buffer
-	err := msgTx.Serialize(&buf)
+	f, err := filters.New(name, n, fp)
 	if err != nil {
-		log.Fatalf("Failed to serialize transaction: %v", err)
+		log.Fatalf("Failed to create the filter: %v", err)
 	}
 
-	// Measure the transaction size
-	txSize := len(buf.Bytes())
-	fmt.Printf("Transaction size: %d bytes\n", txSize)
-
-	return txSize
-}
-
-func getCuckooFilter(publicKeys []string, n uint, fp float64) []byte {
-
-	cf := cuckoofilter.NewFilter(n)
-
 	for _, pubKey := range publicKeys {
-		//_, pubKey := btcec.PrivKeyFromBytes([]byte(privateKey))
-		//pubKeyBytes := pubKey.SerializeCompressed()
 		pubKeyBytes, _ := hex.DecodeString(pubKey)
-		cf.Insert(pubKeyBytes)
+		if err := f.Add(string(pubKeyBytes)); err != nil {
+			log.Fatalf("Failed to add a public key to the %s filter: %v", name, err)
+		}
 
-		if cf.Lookup(pubKeyBytes) {
+		if f.Test(string(pubKeyBytes)) {
 			fmt.Println(pubKeyBytes, " Exists!")
 		}
 	}
 
-	serializedCuckooFilter := cf.Encode()
+	// Serialize the filter
+	serializedFilter, err := f.MarshalBinary()
+	if err != nil {
+		log.Fatalf("Error serializing the %s filter: %v", name, err)
+	}
 
-	return serializedCuckooFilter
+	return serializedFilter
 }
 
 //type FeeInfo struct {
@@ -7,19 +7,24 @@ import (
 	"encoding/csv"
 	"encoding/hex"
 	"fmt"
-	"github.com/bits-and-blooms/bloom/v3"
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/joho/godotenv"
-	cuckoofilter "github.com/panmari/cuckoofilter"
+	"github.com/taurusgroup/multi-party-sig/filters"
+	_ "github.com/taurusgroup/multi-party-sig/filters/bloom"
+	_ "github.com/taurusgroup/multi-party-sig/filters/cuckoo"
+	"github.com/taurusgroup/multi-party-sig/filters/embed"
+	_ "github.com/taurusgroup/multi-party-sig/filters/xor"
 	"log"
 	"math/big"
 	"os"
 	"strconv"
+	"strings"
 )
 
 func main() {
@@ -101,22 +106,30 @@ func main() {
 		log.Fatalf("Failed to get network ID: %v", err)
 	}
 
-	// Define list of csv documents to be created:
-	type FilterFunc func(publicKeys []string, n uint, fp float64) []byte
-
-	type FuncFilePair struct {
-		Func FilterFunc
-		File string
+	// Define the embedding strategies to compare. The blob gas price is set by the BLOB_GAS_PRICE
+	// environment variable, in wei, and defaults to the minimum.
+	strategies := []embed.EthereumStrategy{
+		embed.Calldata{},
+		embed.Log{},
+		embed.BlobSidecar{},
+	}
+	blobGasPrice := big.NewInt(params.BlobTxMinBlobGasprice)
+	if env := os.Getenv("BLOB_GAS_PRICE"); env != "" {
+		if _, ok := blobGasPrice.SetString(env, 10); !ok {
+			log.Fatalf("Invalid blob gas price: %s", env)
+		}
 	}
 
-	funcFilePairs := []FuncFilePair{
-		{getBloomFilter, "EthereumBloomResults.csv"},
-		{getCuckooFilter, "EthereumCuckooResults.csv"},
+	// Define the filters to test, and the csv documents to be created.
+	// The filters are looked up by name in the filters registry, and can be
+	// selected with a comma separated FILTERS environment variable, e.g. FILTERS=bloom,cuckoo
+	filterNames := filters.Names()
+	if env := os.Getenv("FILTERS"); env != "" {
+		filterNames = strings.Split(env, ",")
 	}
 
-	for _, pair := range funcFilePairs {
-		fileName := pair.File
-		filterFunc := pair.Func
+	for _, filterName := range filterNames {
+		fileName := "Ethereum" + strings.ToUpper(filterName[:1]) + filterName[1:] + "Results.csv"
 
 		// Create a new CSV file
 		file, err := os.Create(fileName)
@@ -135,6 +148,21 @@ func main() {
 			log.Fatalf("Failed to write to CSV file: %v", err)
 		}
 
+		// Create a second CSV file comparing the embedding strategies, whose costs are computed offline
+		strategiesFile, err := os.Create("Ethereum" + strings.ToUpper(filterName[:1]) + filterName[1:] + "StrategiesResults.csv")
+		if err != nil {
+			log.Fatalf("Failed to create CSV file: %v", err)
+		}
+		defer strategiesFile.Close()
+
+		strategiesWriter := csv.NewWriter(strategiesFile)
+		defer strategiesWriter.Flush()
+
+		err = strategiesWriter.Write([]string{"partySet", "strategy", "filterSize", "gas", "blobGas", "blobs", "transactionFeeWei"})
+		if err != nil {
+			log.Fatalf("Failed to write to CSV file: %v", err)
+		}
+
 		for _, partySet := range partySets {
 
 			// Generate a set of private keys
@@ -147,7 +175,7 @@ func main() {
 			//serializedFilter := getBloomFilter(pubKey1, pubKey2, pubKey3, 3, 0.0001)
 			//serializedFilter := getBloomFilter(pubKey1, pubKey2, pubKey3, 3, 0.03)
 			//serializedFilter := getBloomFilter(publicKeys, uint(partySet[1]), 0.0001)
-			serializedFilter := filterFunc(publicKeys, uint(partySet[0]), 0.0001)
+			serializedFilter := getFilter(filterName, publicKeys, uint(partySet[0]), 0.0001)
 
 			// Recipient address and amount for test transaction
 			toAddress := common.HexToAddress(ToAddress)
@@ -212,6 +240,49 @@ func main() {
 				log.Fatalf("Failed to write to CSV file: %v", err)
 			}
 
+			// Compare the cost of the embedding strategies, at the suggested gas price and the given blob gas price
+			for _, strategy := range strategies {
+				cost, err := strategy.Cost(serializedFilter)
+				if err != nil {
+					fmt.Printf("Skipping %s: %v\n", strategy.Name(), err)
+					continue
+				}
+
+				// Build the blobs that would be sent, with their KZG commitments and proofs,
+				// and check that the filter can be read back from them
+				blobs := 0
+				if blobStrategy, ok := strategy.(embed.BlobSidecar); ok {
+					sidecar, err := blobStrategy.Sidecar(serializedFilter)
+					if err != nil {
+						log.Fatalf("Failed to build the blob sidecar: %v", err)
+					}
+					extracted, err := blobStrategy.Extract(sidecar, sidecar.BlobHashes())
+					if err != nil || !bytes.Equal(extracted, serializedFilter) {
+						log.Fatalf("Failed to extract the filter from the blob sidecar: %v", err)
+					}
+					blobs = len(sidecar.Blobs)
+					if cost.BlobGas != uint64(blobs)*params.BlobTxBlobGasPerBlob {
+						log.Fatalf("The blob gas %d doesn't match the %d blobs of the sidecar", cost.BlobGas, blobs)
+					}
+				}
+
+				fee := cost.Fee(gasPrice, blobGasPrice)
+				println("The ", strategy.Name(), " embedding costs ", cost.Gas, " gas and ", cost.BlobGas, " blob gas in ", blobs, " blobs, for ", fee.String(), " wei")
+
+				err = strategiesWriter.Write([]string{
+					fmt.Sprintf("%d of %d", partySet[0], partySet[1]),
+					strategy.Name(),
+					strconv.Itoa(len(serializedFilter)),
+					strconv.FormatUint(cost.Gas, 10),
+					strconv.FormatUint(cost.BlobGas, 10),
+					strconv.Itoa(blobs),
+					fee.String(),
+				})
+				if err != nil {
+					log.Fatalf("Failed to write to CSV file: %v", err)
+				}
+			}
+
 		}
 
 	}
@@ -324,7 +395,7 @@ func generatePublicKeys(privateKeys []string) []string {
 	return publicKeys
 }
 
-func getBloomFilter(publicKeys []string, n uint, fp float64) []byte {
+func getFilter(name string, publicKeys []string, n uint, fp float64) []byte {
 
 	if n == 0 {
 		n = 3 // default value
@@ -332,49 +403,30 @@ func getBloomFilter(publicKeys []string, n uint, fp float64) []byte {
 	if fp == 0 {
 		fp = 0.01 // default value
 	}
-	//Create a new Bloom Filter with 1,000,000 items and 0.001% false positive rate
-	bf := bloom.NewWithEstimates(n, fp)
 
-	for _, pubKey := range publicKeys {
-		pubKeyBytes, _ := hex.DecodeString(pubKey)
-		bf.Add(pubKeyBytes)
-
-		if bf.Test(pubKeyBytes) {
-			fmt.Println(pubKeyBytes, " Exists!")
-		}
-	}
-
-	// Serialize the Bloom filter
-	// Assuming `b` is your bloom filter
-	var buf bytes.Buffer
-	_, err := bf.WriteTo(&buf)
+	f, err := filters.New(name, n, fp)
 	if err != nil {
-		log.Fatalf("Error serializing the Bloom filter: %v", err)
+		log.Fatalf("Failed to create the filter: %v", err)
 	}
 
-	serializedBloomFilter := buf.Bytes()
-
-	return serializedBloomFilter
-}
-
-func getCuckooFilter(publicKeys []string, n uint, fp float64) []byte {
-
-	cf := cuckoofilter.NewFilter(n)
-
 	for _, pubKey := range publicKeys {
-		//_, pubKey := btcec.PrivKeyFromBytes([]byte(privateKey))
-		//pubKeyBytes := pubKey.SerializeCompressed()
 		pubKeyBytes, _ := hex.DecodeString(pubKey)
-		cf.Insert(pubKeyBytes)
+		if err := f.Add(string(pubKeyBytes)); err != nil {
+			log.Fatalf("Failed to add a public key to the %s filter: %v", name, err)
+		}
 
-		if cf.Lookup(pubKeyBytes) {
+		if f.Test(string(pubKeyBytes)) {
 			fmt.Println(pubKeyBytes, " Exists!")
 		}
 	}
 
-	serializedCuckooFilter := cf.Encode()
+	// Serialize the filter
+	serializedFilter, err := f.MarshalBinary()
+	if err != nil {
+		log.Fatalf("Error serializing the %s filter: %v", name, err)
+	}
 
-	return serializedCuckooFilter
+	return serializedFilter
 }
 
 //func genPubKeys(PVK_1 string, PVK_2 string, PVK_3 string) (bytes string, bytes2 string, bytes3 string) {